@@ -0,0 +1,408 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/config"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/i18n"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/importer"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/validation"
+)
+
+// filterFlags holds flags which select records.
+type filterFlags struct {
+	user  string
+	drink string
+	since string
+	until string
+	limit int
+}
+
+// addFilterFlags adds flags which select records to flags. -limit is added if withLimit is true.
+func addFilterFlags(flags *flag.FlagSet, withLimit bool) *filterFlags {
+	f := &filterFlags{}
+	flags.StringVar(&f.user, "user", "", "User name")
+	flags.StringVar(&f.drink, "drink", "", "Drink")
+	flags.StringVar(&f.since, "since", "", "First date of records (YYYY-MM-DD)")
+	flags.StringVar(&f.until, "until", "", "Last date of records (YYYY-MM-DD)")
+	if withLimit {
+		flags.IntVar(&f.limit, "limit", 0, "Maximum number of rows (0 for all)")
+	}
+	return f
+}
+
+// filter returns filter of records selected by flags.
+func (f *filterFlags) filter() (models.HydrationFilter, error) {
+	filter := models.HydrationFilter{
+		Username: f.user,
+		Drink:    f.drink,
+		Limit:    f.limit,
+	}
+
+	if len(f.since) > 0 {
+		from, err := time.ParseInLocation(validation.DateFormat, f.since, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("-since is invalid: %q", f.since)
+		}
+		filter.From = from
+	}
+
+	if len(f.until) > 0 {
+		until, err := time.ParseInLocation(validation.DateFormat, f.until, time.Local)
+		if err != nil {
+			return filter, fmt.Errorf("-until is invalid: %q", f.until)
+		}
+		filter.To = until.AddDate(0, 0, 1)
+	}
+
+	return filter, nil
+}
+
+// validateHydration returns hydration filled with values of form, or error listing invalid values.
+func validateHydration(form validation.HydrationForm, now time.Time) (models.Hydration, error) {
+	form.Locale = i18n.English
+	hydration, errs := form.Validate(now)
+	if len(errs) == 0 {
+		return hydration, nil
+	}
+
+	var problems []string
+	for _, block := range []string{validation.BlockDrink, validation.BlockAmount, validation.BlockDrankDate, validation.BlockDrankTime} {
+		if message, ok := errs[block]; ok {
+			problems = append(problems, block+": "+message)
+		}
+	}
+
+	return hydration, errors.New("Invalid record:\n  " + strings.Join(problems, "\n  "))
+}
+
+// writeHydration writes hydration saved in database.
+func (app *app) writeHydration(hydrationID int64) error {
+	hydration, err := app.repo.FetchOne(hydrationID)
+	if err != nil {
+		return err
+	}
+	return app.out.writeHydrations([]models.Hydration{hydration})
+}
+
+// setUpList adds flags of list command and returns it. It lists records matched by filter flags.
+func setUpList(flags *flag.FlagSet) func(app *app) error {
+	filterFlags := addFilterFlags(flags, true)
+
+	return func(app *app) error {
+		filter, err := filterFlags.filter()
+		if err != nil {
+			return err
+		}
+
+		hydrationList, err := app.repo.FetchHydrations(filter)
+		if err != nil {
+			return err
+		}
+
+		return app.out.writeHydrations(hydrationList)
+	}
+}
+
+// setUpAdd adds flags of add command and returns it. It adds record validated like record form.
+func setUpAdd(flags *flag.FlagSet) func(app *app) error {
+	now := time.Now()
+	userName := flags.String("user", "", "User name (required)")
+	drink := flags.String("drink", "", "Drink (required)")
+	amount := flags.String("amount", "", "Amount in ml (required)")
+	drankDate := flags.String("date", now.Format(validation.DateFormat), "Date of drinking (YYYY-MM-DD)")
+	drankTime := flags.String("time", now.Format(validation.TimeFormat), "Time of drinking (HH:MM)")
+
+	return func(app *app) error {
+		if len(*userName) == 0 {
+			return errors.New("-user is required")
+		}
+
+		hydration, err := validateHydration(validation.HydrationForm{
+			Drink:     *drink,
+			Amount:    *amount,
+			DrankDate: *drankDate,
+			DrankTime: *drankTime,
+		}, now)
+		if err != nil {
+			return err
+		}
+		hydration.Username = *userName
+		hydration.UpdatedAt = now
+
+		hydrationID, err := app.repo.Add(hydration, app.actor)
+		if err != nil {
+			return err
+		}
+
+		return app.writeHydration(hydrationID)
+	}
+}
+
+// setUpEdit adds flags of edit command and returns it. It changes values given in flags and keeps others.
+func setUpEdit(flags *flag.FlagSet) func(app *app) error {
+	now := time.Now()
+	hydrationID := flags.Int64("id", 0, "ID of record (required)")
+	drink := flags.String("drink", "", "New drink")
+	amount := flags.String("amount", "", "New amount in ml")
+	drankDate := flags.String("date", "", "New date of drinking (YYYY-MM-DD)")
+	drankTime := flags.String("time", "", "New time of drinking (HH:MM)")
+
+	return func(app *app) error {
+		if *hydrationID <= 0 {
+			return errors.New("-id is required")
+		}
+
+		exHydration, err := app.repo.FetchOne(*hydrationID)
+		if err != nil {
+			return fmt.Errorf("Failed fetching record %d: %v", *hydrationID, err)
+		}
+
+		form := validation.HydrationForm{
+			Drink:     exHydration.Drink,
+			Amount:    strconv.FormatInt(exHydration.Amount, 10),
+			DrankDate: exHydration.DrankAt.Format(validation.DateFormat),
+			DrankTime: exHydration.DrankAt.Format(validation.TimeFormat),
+		}
+		if len(*drink) > 0 {
+			form.Drink = *drink
+		}
+		if len(*amount) > 0 {
+			form.Amount = *amount
+		}
+		if len(*drankDate) > 0 {
+			form.DrankDate = *drankDate
+		}
+		if len(*drankTime) > 0 {
+			form.DrankTime = *drankTime
+		}
+
+		hydration, err := validateHydration(form, now)
+		if err != nil {
+			return err
+		}
+		hydration.ID = exHydration.ID
+		hydration.Username = exHydration.Username
+		hydration.UpdatedAt = now
+
+		// seconds are kept unless drank time is changed.
+		if len(*drankDate) == 0 && len(*drankTime) == 0 {
+			hydration.DrankAt = exHydration.DrankAt
+		}
+
+		if err := app.repo.Update(hydration, app.actor); err != nil {
+			return err
+		}
+
+		return app.writeHydration(hydration.ID)
+	}
+}
+
+// setUpDelete adds flags of delete command and returns it. Deleted record is kept until purged like records deleted in Slack.
+func setUpDelete(flags *flag.FlagSet) func(app *app) error {
+	hydrationID := flags.Int64("id", 0, "ID of record (required)")
+
+	return func(app *app) error {
+		if *hydrationID <= 0 {
+			return errors.New("-id is required")
+		}
+
+		hydration, err := app.repo.FetchOne(*hydrationID)
+		if err != nil {
+			return fmt.Errorf("Failed fetching record %d: %v", *hydrationID, err)
+		}
+
+		if err := app.repo.Delete(hydration, app.actor); err != nil {
+			return err
+		}
+
+		return app.out.writeHydrations([]models.Hydration{hydration})
+	}
+}
+
+// setUpMergeUsers adds flags of merge-users command and returns it.
+func setUpMergeUsers(flags *flag.FlagSet) func(app *app) error {
+	fromUserName := flags.String("from", "", "User whose data is moved (required)")
+	toUserName := flags.String("into", "", "User who receives data (required)")
+
+	return func(app *app) error {
+		if len(*fromUserName) == 0 || len(*toUserName) == 0 {
+			return errors.New("-from and -into are required")
+		}
+		if *fromUserName == *toUserName {
+			return errors.New("-from and -into must be different users")
+		}
+
+		moved, err := app.repo.MergeUsers(*fromUserName, *toUserName, app.actor)
+		if err != nil {
+			return err
+		}
+
+		return app.out.writeCount("moved", moved)
+	}
+}
+
+// setUpPurgeUser adds flags of purge-user command and returns it. It deletes records, favorites and settings
+// of user, and removes user from audit logs.
+func setUpPurgeUser(flags *flag.FlagSet) func(app *app) error {
+	userName := flags.String("user", "", "User whose data is deleted (required)")
+
+	return func(app *app) error {
+		if len(*userName) == 0 {
+			return errors.New("-user is required")
+		}
+
+		deleted, err := app.repo.DeleteUserData(*userName, app.actor)
+		if err != nil {
+			return err
+		}
+
+		return app.out.writeCount("deleted", deleted)
+	}
+}
+
+// setUpPurgeOld adds flags of purge-old command and returns it. It purges records older than retention of config
+// unless flags override it, for running retention from cron instead of server.
+func setUpPurgeOld(flags *flag.FlagSet) func(app *app) error {
+	days := flags.Int("days", 0, "Purge records drunk more than this many days ago (retention.days of config if 0)")
+	mode := flags.String("mode", "", "delete or anonymize (retention.mode of config if empty)")
+
+	return func(app *app) error {
+		retention := app.retention
+		if *days != 0 {
+			retention.Days = *days
+		}
+		if len(*mode) > 0 {
+			retention.Mode = *mode
+		}
+
+		if retention.Days <= 0 {
+			return errors.New("-days must be positive unless retention.days is set in config")
+		}
+		if retention.Mode != config.RetentionDelete && retention.Mode != config.RetentionAnonymize {
+			return fmt.Errorf("-mode is invalid: %q", retention.Mode)
+		}
+
+		cutoff := time.Now().AddDate(0, 0, -retention.Days)
+		purged, err := app.repo.PurgeBefore(cutoff, retention.Mode == config.RetentionAnonymize, app.actor)
+		if err != nil {
+			return err
+		}
+
+		return app.out.writeCount("purged", purged)
+	}
+}
+
+// setUpRenameDrink adds flags of rename-drink command and returns it. It renames drink of records matched by filter flags.
+func setUpRenameDrink(flags *flag.FlagSet) func(app *app) error {
+	filterFlags := addFilterFlags(flags, false)
+	drink := flags.String("to", "", "New name of drink (required)")
+
+	return func(app *app) error {
+		newDrink := strings.TrimSpace(*drink)
+		if len(filterFlags.drink) == 0 || len(newDrink) == 0 {
+			return errors.New("-drink and -to are required")
+		}
+
+		filter, err := filterFlags.filter()
+		if err != nil {
+			return err
+		}
+
+		renamed, err := app.repo.RenameDrink(filter, newDrink, app.actor)
+		if err != nil {
+			return err
+		}
+
+		return app.out.writeCount("renamed", renamed)
+	}
+}
+
+// setUpStats adds flags of stats command and returns it. It shows stats of each user for records matched by filter flags.
+func setUpStats(flags *flag.FlagSet) func(app *app) error {
+	filterFlags := addFilterFlags(flags, true)
+
+	return func(app *app) error {
+		filter, err := filterFlags.filter()
+		if err != nil {
+			return err
+		}
+
+		statsList, err := app.repo.FetchUserStats(filter)
+		if err != nil {
+			return err
+		}
+
+		return app.out.writeStats(statsList)
+	}
+}
+
+// setUpImport adds flags of import command and returns it. It adds records of file which are not recorded yet,
+// and reports status of each record. Nothing is added with -dry-run.
+func setUpImport(flags *flag.FlagSet) func(app *app) error {
+	userName := flags.String("user", "", "User name (required)")
+	filePath := flags.String("file", "", "CSV, JSON or Apple Health XML file to import (required)")
+	format := flags.String("format", "", "File format: csv, json, apple_health or google_fit (default: extension of file)")
+	mappingSpec := flags.String("map", "", `Columns or keys of values such as 'drink= amount="Volume (oz)" unit=oz default_drink=Water' (default: columns of export)`)
+	tz := flags.String("tz", "Local", "Time zone of times without offset such as Asia/Tokyo")
+	dryRun := flags.Bool("dry-run", false, "Report what would change without adding records")
+
+	return func(app *app) error {
+		if len(*userName) == 0 || len(*filePath) == 0 {
+			return errors.New("-user and -file are required")
+		}
+
+		if len(*format) == 0 {
+			*format = importer.FormatOf(*filePath)
+		}
+		if !importer.IsFormat(*format) {
+			return fmt.Errorf("Unknown import format of %s. Set -format to csv, json, apple_health or google_fit", *filePath)
+		}
+
+		mapping, err := importer.ParseMapping(*mappingSpec)
+		if err != nil {
+			return err
+		}
+
+		loc, err := time.LoadLocation(*tz)
+		if err != nil {
+			return fmt.Errorf("-tz is invalid: %v", err)
+		}
+
+		file, err := os.Open(*filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		rows, err := importer.Read(file, *format, mapping, loc, i18n.English, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if err := importer.Prepare(app.repo, rows, *userName); err != nil {
+			return err
+		}
+
+		summary := importer.Summarize(rows)
+		if *dryRun {
+			fmt.Fprintf(os.Stderr, "Dry run: %d new, %d duplicate, %d invalid\n", summary.New, summary.Duplicates, summary.Invalid)
+			return app.out.writeImportRows(rows)
+		}
+
+		added, err := importer.Apply(app.repo, rows, app.actor)
+		if err != nil {
+			return fmt.Errorf("Failed importing after adding %d records: %v", added, err)
+		}
+
+		fmt.Fprintf(os.Stderr, "%d added, %d duplicate, %d invalid\n", added, summary.Duplicates, summary.Invalid)
+		return app.out.writeImportRows(rows)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/config"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/interfaces"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/repositories"
+)
+
+// command describes subcommand of hydrationctl.
+// setUp adds flags of subcommand and returns function which runs it after flags are parsed.
+type command struct {
+	name        string
+	description string
+	setUp       func(flags *flag.FlagSet) func(app *app) error
+}
+
+// app holds values shared by subcommands.
+type app struct {
+	repo      interfaces.HydrationRepository
+	out       output
+	actor     models.Actor
+	retention config.RetentionConfig
+}
+
+var commands = []command{
+	{"list", "List records", setUpList},
+	{"add", "Add record", setUpAdd},
+	{"edit", "Edit record", setUpEdit},
+	{"delete", "Delete record", setUpDelete},
+	{"merge-users", "Move records, favorites and settings of user to another user", setUpMergeUsers},
+	{"rename-drink", "Rename drink of records", setUpRenameDrink},
+	{"stats", "Show number and amount of records of each user", setUpStats},
+	{"import", "Import records of user from CSV or JSON file", setUpImport},
+	{"purge-user", "Delete all data of user who left workspace", setUpPurgeUser},
+	{"purge-old", "Delete or anonymize records older than retention", setUpPurgeOld},
+}
+
+func main() {
+	os.Exit(run())
+}
+
+// run runs subcommand given in arguments and returns exit code.
+func run() int {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: hydrationctl [flags] <command> [command flags]\n\nCommands:\n")
+		for _, cmd := range commands {
+			fmt.Fprintf(os.Stderr, "  %-14s%s\n", cmd.name, cmd.description)
+		}
+		fmt.Fprintf(os.Stderr, "\nRun \"hydrationctl <command> -h\" for flags of command.\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+
+	pConfigsDirPath := flag.String("c", "", "Configs dir path (config.json is not read if empty)")
+	pFormat := flag.String("o", formatTable, "Output format: table, json or csv")
+	pActor := flag.String("actor", currentUserName(), "Name saved in audit logs as who changed records")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		return 2
+	}
+
+	var selected *command
+	for i, cmd := range commands {
+		if cmd.name == flag.Arg(0) {
+			selected = &commands[i]
+		}
+	}
+	if selected == nil {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", flag.Arg(0))
+		flag.Usage()
+		return 2
+	}
+
+	flags := flag.NewFlagSet("hydrationctl "+selected.name, flag.ContinueOnError)
+	runCommand := selected.setUp(flags)
+	if err := flags.Parse(flag.Args()[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	out, err := newOutput(*pFormat, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if len(strings.TrimSpace(*pActor)) == 0 {
+		fmt.Fprintln(os.Stderr, "-actor is required")
+		return 2
+	}
+
+	appConfig, err := config.Load(*pConfigsDirPath, config.ForCLI)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	repo := &repositories.HydrationPgRepository{}
+	if err := repo.Connect(appConfig.Db); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed connecting db: %v\n", err)
+		return 1
+	}
+	defer repo.Close()
+
+	err = runCommand(&app{
+		repo:      repo,
+		out:       out,
+		actor:     models.Actor{Username: *pActor, Source: models.SourceCLI},
+		retention: appConfig.Retention,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+// currentUserName returns login name of OS user, or empty string if it is unknown.
+func currentUserName() string {
+	current, err := user.Current()
+	if err != nil {
+		return ""
+	}
+	return current.Username
+}
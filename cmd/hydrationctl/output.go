@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/importer"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+)
+
+// Output formats.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// timeFormat is format of times in output. Times are saved without time zone, so none is shown.
+const timeFormat = "2006-01-02 15:04:05"
+
+type (
+	// output writes results of subcommands in selected format.
+	output struct {
+		format string
+		w      io.Writer
+	}
+
+	// hydrationRecord describes hydration in JSON output.
+	hydrationRecord struct {
+		ID        int64  `json:"id"`
+		User      string `json:"user"`
+		Drink     string `json:"drink"`
+		Amount    int64  `json:"amount"`
+		DrankAt   string `json:"drank_at"`
+		UpdatedAt string `json:"updated_at"`
+	}
+
+	// importRecord describes row of imported file in JSON output.
+	importRecord struct {
+		Row     int    `json:"row"`
+		Status  string `json:"status"`
+		Drink   string `json:"drink"`
+		Amount  int64  `json:"amount"`
+		DrankAt string `json:"drank_at"`
+		Problem string `json:"problem"`
+	}
+
+	// statsRecord describes stats of user in JSON output.
+	statsRecord struct {
+		User         string `json:"user"`
+		Records      int64  `json:"records"`
+		TotalAmount  int64  `json:"total_amount"`
+		Days         int64  `json:"days"`
+		FirstDrankAt string `json:"first_drank_at"`
+		LastDrankAt  string `json:"last_drank_at"`
+	}
+)
+
+// newOutput returns output writing to w in format.
+func newOutput(format string, w io.Writer) (output, error) {
+	switch format {
+	case formatTable, formatJSON, formatCSV:
+		return output{format: format, w: w}, nil
+	}
+	return output{}, fmt.Errorf("Unknown output format: %s", format)
+}
+
+// write writes rows under header, or records as JSON in JSON format.
+func (out output) write(header []string, rows [][]string, records interface{}) error {
+	switch out.format {
+	case formatJSON:
+		encoder := json.NewEncoder(out.w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(records)
+	case formatCSV:
+		w := csv.NewWriter(out.w)
+		if err := w.Write(header); err != nil {
+			return err
+		}
+		return w.WriteAll(rows)
+	}
+
+	w := tabwriter.NewWriter(out.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.ReplaceAll(cell, "\t", " ")
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+// writeHydrations writes list of hydration data.
+func (out output) writeHydrations(hydrationList []models.Hydration) error {
+	records := []hydrationRecord{}
+	var rows [][]string
+	for _, hydration := range hydrationList {
+		record := hydrationRecord{
+			ID:        hydration.ID,
+			User:      hydration.Username,
+			Drink:     hydration.Drink,
+			Amount:    hydration.Amount,
+			DrankAt:   hydration.DrankAt.Format(timeFormat),
+			UpdatedAt: hydration.UpdatedAt.Format(timeFormat),
+		}
+		records = append(records, record)
+		rows = append(rows, []string{
+			strconv.FormatInt(record.ID, 10),
+			record.User,
+			record.Drink,
+			strconv.FormatInt(record.Amount, 10),
+			record.DrankAt,
+			record.UpdatedAt,
+		})
+	}
+
+	return out.write([]string{"id", "user", "drink", "amount", "drank_at", "updated_at"}, rows, records)
+}
+
+// writeStats writes stats of users.
+func (out output) writeStats(statsList []models.UserStats) error {
+	records := []statsRecord{}
+	var rows [][]string
+	for _, stats := range statsList {
+		record := statsRecord{
+			User:         stats.Username,
+			Records:      stats.Records,
+			TotalAmount:  stats.TotalAmount,
+			Days:         stats.Days,
+			FirstDrankAt: stats.FirstDrankAt.Format(timeFormat),
+			LastDrankAt:  stats.LastDrankAt.Format(timeFormat),
+		}
+		records = append(records, record)
+		rows = append(rows, []string{
+			record.User,
+			strconv.FormatInt(record.Records, 10),
+			strconv.FormatInt(record.TotalAmount, 10),
+			strconv.FormatInt(record.Days, 10),
+			record.FirstDrankAt,
+			record.LastDrankAt,
+		})
+	}
+
+	return out.write([]string{"user", "records", "total_amount", "days", "first_drank_at", "last_drank_at"}, rows, records)
+}
+
+// writeImportRows writes status of each row of imported file.
+func (out output) writeImportRows(rows []importer.Row) error {
+	records := []importRecord{}
+	var tableRows [][]string
+	for _, row := range rows {
+		record := importRecord{
+			Row:     row.Number,
+			Status:  row.Status,
+			Drink:   row.Hydration.Drink,
+			Amount:  row.Hydration.Amount,
+			Problem: row.Problem,
+		}
+		if !row.Hydration.DrankAt.IsZero() {
+			record.DrankAt = row.Hydration.DrankAt.Format(timeFormat)
+		}
+		records = append(records, record)
+		tableRows = append(tableRows, []string{
+			strconv.Itoa(record.Row),
+			record.Status,
+			record.Drink,
+			strconv.FormatInt(record.Amount, 10),
+			record.DrankAt,
+			record.Problem,
+		})
+	}
+
+	return out.write([]string{"row", "status", "drink", "amount", "drank_at", "problem"}, tableRows, records)
+}
+
+// writeCount writes number of records changed by subcommand under name.
+func (out output) writeCount(name string, count int64) error {
+	return out.write([]string{name}, [][]string{{strconv.FormatInt(count, 10)}}, map[string]int64{name: count})
+}
@@ -1,448 +1,2114 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	echo "github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
-	jsonpointer "github.com/mattn/go-jsonpointer"
-	"github.com/pirosuke/slack-bot-hydration/internal/file"
+	"github.com/pirosuke/slack-bot-hydration/configs"
 	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/config"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/export"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/health"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/i18n"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/importer"
 	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/interfaces"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/jobs"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/logging"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/metadata"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/metrics"
 	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
 	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/repositories"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/router"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/routing"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/slack"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/tracing"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/validation"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/version"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/views"
 )
 
 var (
-	repo interfaces.HydrationRepository
+	repo      repositories.InstrumentedRepository
+	slackRepo *repositories.SlackRepository
+	readiness *health.Checker
+
+	// background runs work which continues after responding to Slack.
+	background = &jobs.Runner{}
+)
+
+const (
+	// undoWindow is duration while operations can be undone with buttons.
+	undoWindow = 5 * time.Minute
+
+	// viewsReloadInterval is interval of checking view override directory for changes.
+	viewsReloadInterval = 2 * time.Second
+
+	// readinessTimeout is time limit of all readiness checks.
+	readinessTimeout = 10 * time.Second
+
+	// slackAuthTTL is duration while result of Slack auth.test is reused, as the API is rate limited.
+	slackAuthTTL = time.Minute
+
+	// dailyStatsInterval is interval of updating metrics of today's records.
+	dailyStatsInterval = time.Minute
+
+	// deliveryTTL is duration while retries of Slack deliveries are ignored.
+	// Slack retries deliveries within a few minutes.
+	deliveryTTL = time.Hour
+
+	// retentionInterval is interval of purging records older than retention.
+	retentionInterval = 24 * time.Hour
+
+	// retentionActor is actor of audit logs of records purged by retention.
+	retentionActor = "retention"
+
+	// shutdownTimeout is time limit of finishing in-flight requests and background jobs on shutdown.
+	shutdownTimeout = 20 * time.Second
+	// jobCancelTimeout is time limit of stopping background jobs canceled on shutdown, and of flushing spans.
+	jobCancelTimeout = 5 * time.Second
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: slack_bot_hydration [flags]\n")
+		flag.PrintDefaults()
+	}
+
+	pConfigsDirPath := flag.String("c", "", "Configs dir path (config.json is not read if empty)")
+	flag.Parse()
+
+	configsDirPath := *pConfigsDirPath
+
+	appConfig, err := config.Load(configsDirPath, config.ForServer)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	logOutput, err := logging.OpenOutput(appConfig.LogOutputPath())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer logOutput.Close()
+
+	logLevel, _ := logging.ParseLevel(appConfig.LogLevel)
+	slog.SetDefault(logging.New(logOutput, logLevel))
+
+	shutdownTracing, err := tracing.Setup(context.Background(), appConfig.Tracing, "slack_bot_hydration")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	defaultViews, err := fs.Sub(configs.Views, "views")
+	if err != nil {
+		panic(err)
+	}
+
+	viewSet, err := views.New(defaultViews, appConfig.ViewsDirPath)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := SetUp(appConfig, viewSet); err != nil {
+		slog.Error("Failed setting up", "error", err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e.Logger.SetLevel(log.INFO)
+	e.Logger.SetOutput(logOutput)
+
+	e.Use(tracing.Middleware())
+	e.Use(logging.Middleware())
+	e.Use(middleware.Recover())
+
+	if len(appConfig.ViewsDirPath) > 0 {
+		stopWatch := viewSet.Watch(viewsReloadInterval, func(err error) {
+			if err != nil {
+				slog.Error("Failed reloading views", "error", err)
+				return
+			}
+			slog.Info("Reloaded views", "dir", appConfig.ViewsDirPath)
+		})
+		defer stopWatch()
+	}
+
+	verifySignature := router.VerifySignature(appConfig.Slack.SigningSecret)
+
+	e.POST("/", newRouter(appConfig, configsDirPath).Serve, verifySignature)
+	e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+	e.GET("/healthz", handleHealthz)
+	e.GET("/readyz", handleReadyz)
+	e.GET("/version", handleVersion)
+
+	e.POST("/events", func(c echo.Context) error {
+		return eventGateway(c, appConfig, configsDirPath)
+	}, verifySignature)
+
+	e.POST("/commands", func(c echo.Context) error {
+		return commandGateway(c, appConfig, configsDirPath)
+	}, verifySignature)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go refreshDailyStats(ctx, dailyStatsInterval)
+	go purgeDeliveries(ctx, deliveryTTL)
+	go applyRetention(ctx, appConfig.Retention, retentionInterval)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Starting server", "host", appConfig.ServerHost, "version", version.Version)
+		serverErr <- e.Start(appConfig.ServerHost)
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Server stopped", "error", err)
+		}
+	case <-ctx.Done():
+		slog.Info("Shutting down")
+	}
+
+	if shutdown(e, shutdownTracing) {
+		TearDown()
+	} else {
+		slog.Warn("Left repository open as background jobs are still running")
+	}
+}
+
+// shutdown stops accepting requests and waits for in-flight requests and background jobs within shutdownTimeout.
+// Jobs still running after the timeout are logged and canceled, and are waited for jobCancelTimeout more.
+// Spans which are not exported yet are flushed after jobs finish. It returns whether all jobs finished.
+func shutdown(e *echo.Echo, shutdownTracing func(context.Context) error) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := e.Shutdown(ctx); err != nil {
+		slog.Error("Failed finishing in-flight requests", "error", err)
+	}
+
+	finished := true
+	if err := background.Wait(ctx); err != nil {
+		slog.Error("Failed finishing background jobs, so they are canceled", "error", err)
+		background.Cancel()
+
+		cancelCtx, cancelWait := context.WithTimeout(context.Background(), jobCancelTimeout)
+		defer cancelWait()
+		if err := background.Wait(cancelCtx); err != nil {
+			slog.Error("Failed canceling background jobs", "error", err)
+			finished = false
+		}
+	} else {
+		slog.Info("Finished all requests and background jobs")
+	}
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), jobCancelTimeout)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Failed exporting spans", "error", err)
+	}
+
+	return finished
+}
+
+// SetUp initializes App
+func SetUp(appConfig config.Config, viewSet *views.Set) error {
+	if appConfig.Db.Client == "postgresql" {
+		repo = repositories.InstrumentedRepository{HydrationRepository: &repositories.HydrationPgRepository{}}
+	}
+
+	slackRepo = &repositories.SlackRepository{
+		Token: appConfig.Slack.Token,
+		Views: viewSet,
+	}
+
+	err := repo.Connect(appConfig.Db)
+	if err != nil {
+		return fmt.Errorf("Failed connecting db: %v", err)
+	}
+
+	readiness = health.New(readinessTimeout)
+	readiness.Add("db", repo.Ping)
+	readiness.Add("views", func() error {
+		if len(viewSet.Names()) == 0 {
+			return fmt.Errorf("No views are loaded")
+		}
+		return nil
+	})
+	readiness.AddCached("slack", slackAuthTTL, slackRepo.AuthTest)
+
+	// fail fast instead of serving while dependencies are unreachable.
+	if err := readiness.Run().Err(); err != nil {
+		repo.Close()
+		return err
+	}
+
+	return nil
+}
+
+// TearDown destructs App. It must be called after background jobs finish or are canceled as they use repository.
+func TearDown() {
+	repo.Close()
+	slog.Info("Closed repository")
+}
+
+// handleHealthz answers that process is alive.
+func handleHealthz(c echo.Context) error {
+	return c.String(http.StatusOK, health.StatusOK)
+}
+
+// handleReadyz answers whether database, views and Slack API are usable.
+func handleReadyz(c echo.Context) error {
+	report := readiness.Run()
+	if !report.OK() {
+		slog.WarnContext(c.Request().Context(), "Not ready", "error", report.Err())
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// handleVersion answers build information.
+func handleVersion(c echo.Context) error {
+	return c.JSON(http.StatusOK, version.Get())
+}
+
+// newRouter registers handlers of interactions.
+func newRouter(appConfig config.Config, configsDirPath string) *router.Router {
+	r := router.New()
+	r.Use(
+		router.Recover(),
+		router.Trace(),
+		router.Logger(),
+		router.Metrics(observeInteraction),
+		router.Deduplicate(claimDelivery, releaseDelivery),
+		router.UserLookup(func(ctx context.Context, userID string) (string, error) {
+			return slackRepo.WithContext(ctx).FetchUserName(userID)
+		}),
+		router.LocaleLookup(func(ctx context.Context, user slack.User, userName string) string {
+			return userLocale(ctx, userName, user.ID, user.Locale)
+		}),
+	)
+
+	anyInteraction := func(handle func(echo.Context, config.Config, string, slack.Interaction) error) router.HandlerFunc {
+		return func(c echo.Context, interaction slack.Interaction) error {
+			return handle(c, appConfig, configsDirPath, interaction)
+		}
+	}
+	messageAction := func(handle func(echo.Context, config.Config, string, slack.MessageActionPayload) error) router.HandlerFunc {
+		return func(c echo.Context, interaction slack.Interaction) error {
+			return handle(c, appConfig, configsDirPath, interaction.(slack.MessageActionPayload))
+		}
+	}
+	blockActions := func(handle func(echo.Context, config.Config, string, slack.BlockActionsPayload) error) router.HandlerFunc {
+		return func(c echo.Context, interaction slack.Interaction) error {
+			return handle(c, appConfig, configsDirPath, interaction.(slack.BlockActionsPayload))
+		}
+	}
+	viewSubmission := func(handle func(echo.Context, config.Config, string, slack.ViewSubmissionPayload) error) router.HandlerFunc {
+		return func(c echo.Context, interaction slack.Interaction) error {
+			return handle(c, appConfig, configsDirPath, interaction.(slack.ViewSubmissionPayload))
+		}
+	}
+
+	r.Handle(slack.InteractionShortcut, "hydration__record_drink", anyInteraction(HandleOpenHydrationForm))
+	r.Handle(slack.InteractionMessageAction, "hydration__favorites", messageAction(HandleOpenFavoritesDialog))
+
+	r.Handle(slack.InteractionBlockActions, "hydration__record_drink", anyInteraction(HandleOpenHydrationForm))
+	r.Handle(slack.InteractionBlockActions, "hydration__update_drink", blockActions(HandleOpenHydrationUpdateForm))
+	r.Handle(slack.InteractionBlockActions, "hydration__delete_drink", blockActions(HandleHydrationDelete))
+	r.Handle(slack.InteractionBlockActions, "hydration__repeat_drink", blockActions(HandleHydrationRepeat))
+	r.Handle(slack.InteractionBlockActions, "hydration__open_settings", blockActions(HandleOpenSettingsForm))
+	r.Handle(slack.InteractionBlockActions, "hydration__pin_drink", blockActions(HandlePinDrink))
+	r.Handle(slack.InteractionBlockActions, "hydration__unpin_drink", blockActions(HandleUnpinDrink))
+	r.Handle(slack.InteractionBlockActions, "hydration__undo", blockActions(HandleUndo))
+	r.HandlePrefix(slack.InteractionBlockActions, "hydration__quick_log_", blockActions(HandleQuickLog))
+	r.HandlePrefix(slack.InteractionBlockActions, "hydration__export_", blockActions(HandleExport))
+	r.Handle(slack.InteractionBlockActions, "hydration__import_confirm", blockActions(HandleImportConfirm))
+	r.Handle(slack.InteractionBlockActions, "hydration__import_cancel", blockActions(HandleImportCancel))
+	r.Handle(slack.InteractionBlockActions, "hydration__delete_my_data", blockActions(HandleDeleteMyData))
+	r.Handle(slack.InteractionBlockActions, "hydration__purge_user", blockActions(HandlePurgeUser))
+
+	r.Handle(slack.InteractionViewSubmission, "hydration__record_form", viewSubmission(HandleHydrationFormAddSubmission))
+	r.Handle(slack.InteractionViewSubmission, "hydration__update_form", viewSubmission(HandleHydrationFormUpdateSubmission))
+	r.Handle(slack.InteractionViewSubmission, "hydration__settings_form", viewSubmission(HandleSettingsFormSubmission))
+
+	// nothing is kept while modals are open, so closing needs no cleanup.
+	r.HandlePrefix(slack.InteractionViewClosed, "", func(c echo.Context, interaction slack.Interaction) error {
+		return c.String(http.StatusOK, "")
+	})
+
+	return r
+}
+
+// logError logs error of handler or its background job with request ID of ctx.
+func logError(ctx context.Context, err error) {
+	slog.ErrorContext(ctx, "Failed handling request", "error", err)
+}
+
+// observeInteraction records outcome of handled interaction.
+func observeInteraction(route router.Route, outcome string, elapsed time.Duration) {
+	metrics.ObserveInteraction(route.PayloadType, route.ID, outcome, elapsed)
+}
+
+// claimDelivery records Slack delivery and returns false if it is already received within deliveryTTL.
+func claimDelivery(ctx context.Context, deliveryID string) (bool, error) {
+	return repo.WithContext(ctx).ClaimDelivery(deliveryID, deliveryTTL)
+}
+
+// releaseDelivery deletes record of Slack delivery whose handling failed, so that its retry is handled.
+func releaseDelivery(ctx context.Context, deliveryID string) error {
+	return repo.WithContext(ctx).ReleaseDelivery(deliveryID)
+}
+
+// purgeDeliveries deletes expired records of Slack deliveries every interval until ctx is done.
+func purgeDeliveries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := repo.WithContext(ctx).DeleteExpiredDeliveries()
+		if err != nil {
+			slog.Error("Failed deleting expired deliveries", "error", err)
+			continue
+		}
+		slog.Debug("Deleted expired deliveries", "count", deleted)
+	}
+}
+
+// applyRetention deletes or anonymizes records older than days of retention every interval until ctx is done.
+// Nothing is done if retention is disabled.
+func applyRetention(ctx context.Context, retention config.RetentionConfig, interval time.Duration) {
+	if retention.Days <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	actor := models.Actor{Username: retentionActor, Source: models.SourceRetention}
+	for {
+		cutoff := time.Now().AddDate(0, 0, -retention.Days)
+		purged, err := repo.WithContext(ctx).PurgeBefore(cutoff, retention.Mode == config.RetentionAnonymize, actor)
+		if err != nil {
+			slog.Error("Failed purging old records", "error", err)
+		} else {
+			slog.Info("Purged old records", "count", purged, "mode", retention.Mode, "before", cutoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshDailyStats updates metrics of today's records every interval until ctx is done.
+func refreshDailyStats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		stats, err := repo.WithContext(ctx).FetchDailyStats()
+		if err == nil {
+			metrics.SetDailyStats(stats.Records, stats.Users)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func eventGateway(c echo.Context, appConfig config.Config, configsDirPath string) error {
+	var payload slack.EventPayload
+	err := json.NewDecoder(c.Request().Body).Decode(&payload)
+	if err != nil {
+		logError(c.Request().Context(), err)
+		return c.String(http.StatusBadRequest, "Error")
+	}
+
+	if deliveryID := payload.DeliveryID(); len(deliveryID) > 0 {
+		claimed, err := claimDelivery(c.Request().Context(), deliveryID)
+		if err != nil {
+			logError(c.Request().Context(), err)
+		} else if !claimed {
+			slog.InfoContext(c.Request().Context(), "Ignored duplicate delivery",
+				"event_type", payload.Event.Type,
+				"retry_num", c.Request().Header.Get(slack.HeaderRetryNum))
+			metrics.ObserveDuplicateDelivery(payload.Type)
+			return c.NoContent(http.StatusOK)
+		} else {
+			err = handleEvent(c, appConfig, configsDirPath, payload)
+			if err != nil || c.Response().Status >= http.StatusInternalServerError {
+				if releaseErr := releaseDelivery(c.Request().Context(), deliveryID); releaseErr != nil {
+					logError(c.Request().Context(), releaseErr)
+				}
+			}
+			return err
+		}
+	}
+
+	return handleEvent(c, appConfig, configsDirPath, payload)
+}
+
+// handleEvent handles event of Events API.
+func handleEvent(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.EventPayload) error {
+	switch payload.Type {
+	case "url_verification":
+		return c.String(http.StatusOK, payload.Challenge)
+	case "event_callback":
+		switch payload.Event.Type {
+		case "app_home_opened":
+			return HandleAppHomeOpened(c, appConfig, configsDirPath, payload.Event)
+		case "message":
+			if payload.Event.Subtype == "file_share" && payload.Event.ChannelType == "im" && len(payload.Event.BotID) == 0 {
+				return HandleFileShare(c, appConfig, configsDirPath, payload.Event)
+			}
+		default:
+			slog.WarnContext(c.Request().Context(), "Unrecognized event type", "type", payload.Event.Type)
+		}
+	}
+
+	return c.String(http.StatusOK, "")
+}
+
+func commandGateway(c echo.Context, appConfig config.Config, configsDirPath string) error {
+	text := strings.Fields(c.FormValue("text"))
+	subCommand := ""
+	if len(text) > 0 {
+		subCommand = text[0]
+	}
+
+	locale := userLocale(c.Request().Context(), c.FormValue("user_name"), c.FormValue("user_id"), "")
+
+	switch subCommand {
+	case "undo":
+		return HandleUndoCommand(c, appConfig, configsDirPath, locale)
+	case "export":
+		return HandleExportCommand(c, appConfig, configsDirPath, locale, text[1:])
+	case "delete-my-data":
+		return HandleDeleteMyDataCommand(c, appConfig, configsDirPath, locale)
+	case "audit":
+		if isAdmin(appConfig, c.FormValue("user_id")) {
+			return HandleAuditCommand(c, appConfig, configsDirPath, locale, text[1:])
+		}
+	case "purge":
+		if isAdmin(appConfig, c.FormValue("user_id")) {
+			return HandlePurgeCommand(c, appConfig, configsDirPath, locale, text[1:])
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"response_type": "ephemeral",
+		"text":          i18n.T(locale, "command.usage", c.FormValue("command"), c.FormValue("command"), c.FormValue("command")),
+	})
+}
+
+// isAdmin returns whether user ID is listed in admins of config.
+// It must be given user ID of request whose signature is verified.
+func isAdmin(appConfig config.Config, userID string) bool {
+	for _, admin := range appConfig.Admins {
+		if admin == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleAuditCommand shows audit logs of user or record.
+func HandleAuditCommand(c echo.Context, appConfig config.Config, configsDirPath string, locale string, args []string) error {
+	slackRepo := slackRepo.WithContext(c.Request().Context())
+	userID := c.FormValue("user_id")
+	usage := i18n.T(locale, "command.audit_usage", c.FormValue("command"), c.FormValue("command"))
+
+	filter := models.AuditLogFilter{
+		Limit: 20,
+	}
+
+	if len(args) != 2 {
+		return c.JSON(http.StatusOK, map[string]string{
+			"response_type": "ephemeral",
+			"text":          usage,
+		})
+	}
+
+	switch args[0] {
+	case "user":
+		filter.Username = strings.TrimPrefix(args[1], "@")
+	case "record":
+		hydrationID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || hydrationID <= 0 {
+			return c.JSON(http.StatusOK, map[string]string{
+				"response_type": "ephemeral",
+				"text":          usage,
+			})
+		}
+		filter.HydrationID = hydrationID
+	default:
+		return c.JSON(http.StatusOK, map[string]string{
+			"response_type": "ephemeral",
+			"text":          usage,
+		})
+	}
+
+	responseURL := c.FormValue("response_url")
+
+	background.Go(c.Request().Context(), jobs.Describe("show audit logs", "user", filter.Username, "hydration_id", filter.HydrationID), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		repo := repo.WithContext(ctx)
+
+		auditLogs, err := repo.FetchAuditLogs(filter)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		_, err = slackRepo.Respond(responseURL, formatAuditLogs(locale, userLocation(ctx, slackRepo, userID), auditLogs), false)
+		if err != nil {
+			logError(ctx, err)
+		}
+	})
+
+	return c.String(http.StatusOK, "")
+}
+
+// formatAuditLogs returns audit logs as text for slash command response.
+// Times are shown in loc.
+func formatAuditLogs(locale string, loc *time.Location, auditLogs []models.AuditLog) string {
+	if len(auditLogs) == 0 {
+		return i18n.T(locale, "command.audit_none")
+	}
+
+	var lines []string
+	for _, auditLog := range auditLogs {
+		before := auditLog.Before
+		if len(before) == 0 {
+			before = "-"
+		}
+		after := auditLog.After
+		if len(after) == 0 {
+			after = "-"
+		}
+
+		lines = append(lines, fmt.Sprintf("%s #%d %s by %s (%s)\n  before: `%s`\n  after: `%s`",
+			export.InLocation(auditLog.CreatedAt, loc).Format("2006/01/02 15:04:05"),
+			auditLog.HydrationID,
+			auditLog.Action,
+			slack.EscapeMrkdwn(auditLog.Actor),
+			auditLog.Source,
+			slack.EscapeMrkdwn(before),
+			slack.EscapeMrkdwn(after),
+		))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// HandleUndoCommand undoes last operation of user.
+func HandleUndoCommand(c echo.Context, appConfig config.Config, configsDirPath string, locale string) error {
+	slackRepo := slackRepo.WithContext(c.Request().Context())
+	userID := c.FormValue("user_id")
+	userName := c.FormValue("user_name")
+	responseURL := c.FormValue("response_url")
+
+	background.Go(c.Request().Context(), jobs.Describe("undo last operation", "user", userName), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		repo := repo.WithContext(ctx)
+
+		revision, err := repo.FetchLastRevision(userName)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		text := i18n.T(locale, "command.undo_none")
+		if revision.ID > 0 {
+			err = undoRevision(ctx, appConfig, configsDirPath, revision, models.Actor{Username: userName, Source: models.SourceCommand}, userID)
+			if err != nil {
+				logError(ctx, err)
+				text = i18n.T(locale, "command.undo_failed")
+			} else {
+				text = i18n.T(locale, "command.undo_done")
+			}
+		}
+
+		_, err = slackRepo.Respond(responseURL, text, false)
+		if err != nil {
+			logError(ctx, err)
+		}
+	})
+
+	return c.String(http.StatusOK, "")
+}
+
+// HandleUndo undoes operation selected with undo button.
+func HandleUndo(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	slackRepo := slackRepo.ForLocale(router.Locale(c)).WithContext(c.Request().Context())
+	revisionID, err := strconv.ParseInt(payload.Actions[0].Value, 10, 64)
+	if err != nil {
+		slog.WarnContext(c.Request().Context(), "Invalid undo request", "value", payload.Actions[0].Value)
+		return c.String(http.StatusBadRequest, "Error")
+	}
+
+	userName := router.UserName(c, payload)
+	userID := payload.User.ID
+	triggerID := payload.TriggerID
+	responseURL := payload.ResponseURL
+
+	background.Go(c.Request().Context(), jobs.Describe("undo", "user", userName, "revision_id", revisionID), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		repo := repo.WithContext(ctx)
+
+		revision, err := repo.FetchRevision(revisionID)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		lastRevision, err := repo.FetchLastHydrationRevision(revision.HydrationID)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		// only latest operation of record can be undone so that later changes are not lost.
+		if revision.Username != userName || lastRevision.ID != revision.ID || revision.Age > undoWindow {
+			_, err = slackRepo.ShowAlert(triggerID, i18n.T(slackRepo.Locale, "alert.undo_failed.title"), i18n.T(slackRepo.Locale, "alert.undo_failed.text"))
+			if err != nil {
+				logError(ctx, err)
+			}
+			return
+		}
+
+		err = undoRevision(ctx, appConfig, configsDirPath, revision, models.Actor{Username: userName, Source: models.SourceButton}, userID)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		if len(responseURL) > 0 {
+			_, err = slackRepo.Respond(responseURL, i18n.T(slackRepo.Locale, "undo.done"), true)
+			if err != nil {
+				logError(ctx, err)
+			}
+		}
+	})
+
+	return c.String(http.StatusOK, "")
+}
+
+// undoRevision reverts operation of revision and restores result message.
+func undoRevision(ctx context.Context, appConfig config.Config, configsDirPath string, revision models.HydrationRevision, actor models.Actor, userID string) error {
+	repo := repo.WithContext(ctx)
+	hydration, err := repo.Undo(revision, actor)
+	if err != nil {
+		return err
+	}
+	slackRepo := slackRepo.ForLocale(userLocale(ctx, hydration.Username, userID, "")).WithContext(ctx)
+
+	refreshHome(ctx, appConfig, configsDirPath, userID, hydration.Username)
+
+	switch revision.Operation {
+	case models.OperationAdd:
+		if len(hydration.Channel) > 0 {
+			_, err = slackRepo.DeleteMessage(hydration.Channel, hydration.MessageTS)
+		}
+	case models.OperationUpdate:
+		if len(hydration.Channel) > 0 {
+			dailyAmount, err := repo.FetchDailyAmount(hydration.Username)
+			if err != nil {
+				return err
+			}
+			_, err = slackRepo.PostHydrationUpdateResult(hydration.Username, hydration.Channel, hydration.MessageTS, hydration, dailyAmount, userLocation(ctx, slackRepo, userID))
+			if err != nil {
+				return err
+			}
+		}
+	case models.OperationDelete:
+		channel := hydration.Channel
+		if len(channel) == 0 {
+			channel, err = resultChannel(ctx, appConfig, "", hydration.Username, routing.Destination{UserID: userID})
+			if err != nil {
+				return err
+			}
+		}
+
+		dailyAmount, err := repo.FetchDailyAmount(hydration.Username)
+		if err != nil {
+			return err
+		}
+
+		resp, err := slackRepo.PostHydrationAddResult(hydration.Username, channel, hydration, dailyAmount, userLocation(ctx, slackRepo, userID))
+		if err != nil {
+			return err
+		}
+
+		_, err = saveResultMessage(ctx, hydration.ID, resp)
+		if err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// resultChannel returns channel where result message of user is posted.
+func resultChannel(ctx context.Context, appConfig config.Config, teamID string, userName string, destination routing.Destination) (string, error) {
+	settings, err := repo.WithContext(ctx).FetchUserSettings(userName)
+	if err != nil {
+		return "", err
+	}
+
+	return routing.ResultChannel(appConfig.Routing.ForWorkspace(teamID), settings, destination), nil
+}
+
+// saveResultMessage saves location of result message posted for hydration.
+func saveResultMessage(ctx context.Context, hydrationID int64, resp []byte) (slack.Response, error) {
+	var message slack.Response
+	err := json.Unmarshal(resp, &message)
+	if err != nil {
+		return message, err
+	}
+
+	if !message.Ok {
+		return message, fmt.Errorf("Failed posting message: %s", message.Error)
+	}
+
+	return message, repo.WithContext(ctx).SetMessage(hydrationID, message.Channel, message.TS)
+}
+
+// postUndoMessage posts undo button of latest operation of hydration to user.
+func postUndoMessage(ctx context.Context, slackRepo *repositories.SlackRepository, channel string, userID string, hydrationID int64) error {
+	if len(channel) == 0 || len(userID) == 0 {
+		return nil
+	}
+
+	revision, err := repo.WithContext(ctx).FetchLastHydrationRevision(hydrationID)
+	if err != nil || revision.ID == 0 {
+		return err
+	}
+
+	_, err = slackRepo.PostUndoMessage(channel, userID, revision)
+	return err
+}
+
+// HandleExportCommand sends all records of user as file in format given in args, which is CSV by default.
+func HandleExportCommand(c echo.Context, appConfig config.Config, configsDirPath string, locale string, args []string) error {
+	format := export.FormatCSV
+	if len(args) > 0 {
+		format = strings.ToLower(args[0])
+	}
+
+	if len(args) > 1 || !export.IsFormat(format) {
+		return c.JSON(http.StatusOK, map[string]string{
+			"response_type": "ephemeral",
+			"text":          i18n.T(locale, "command.export_usage", c.FormValue("command")),
+		})
+	}
+
+	userID := c.FormValue("user_id")
+	userName := c.FormValue("user_name")
+
+	background.Go(c.Request().Context(), jobs.Describe("export history", "user", userName, "format", format), func(ctx context.Context) {
+		if err := exportHistory(ctx, userID, userName, locale, format); err != nil {
+			logError(ctx, err)
+		}
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"response_type": "ephemeral",
+		"text":          i18n.T(locale, "command.export_started"),
+	})
+}
+
+// HandleExport sends all records of user as file in format of pressed button.
+func HandleExport(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	format := payload.Actions[0].Value
+	if !export.IsFormat(format) {
+		slog.WarnContext(c.Request().Context(), "Invalid export format", "format", format)
+		return c.String(http.StatusBadRequest, "Error")
+	}
+
+	userID := payload.User.ID
+	userName := router.UserName(c, payload)
+	locale := router.Locale(c)
+
+	background.Go(c.Request().Context(), jobs.Describe("export history", "user", userName, "format", format), func(ctx context.Context) {
+		if err := exportHistory(ctx, userID, userName, locale, format); err != nil {
+			logError(ctx, err)
+		}
+	})
+
+	return c.String(http.StatusOK, "")
+}
+
+// exportHistory uploads all records of user to DM as file in format, with times in time zone of user.
+// Records are written to temporary file one by one so that long history is not loaded into memory.
+func exportHistory(ctx context.Context, userID string, userName string, locale string, format string) error {
+	repo := repo.WithContext(ctx)
+	slackRepo := slackRepo.ForLocale(locale).WithContext(ctx)
+	loc := userLocation(ctx, slackRepo, userID)
+
+	file, err := os.CreateTemp("", "hydration-export-*."+export.Extension(format))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	err = writeHistory(file, repo, userName, format, loc)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	channel, err := slackRepo.OpenDirectMessage(userID)
+	if err != nil {
+		return err
+	}
+
+	resp, err := slackRepo.UploadFile(channel, export.FileName(userName, format, time.Now()), export.Extension(format), file.Name(), i18n.T(locale, "export.caption"))
+	if err != nil {
+		return err
+	}
+
+	var result slack.Response
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return err
+	}
+	if !result.Ok {
+		return fmt.Errorf("Failed uploading export: %s", result.Error)
+	}
+
+	return nil
+}
+
+// userLocation returns time zone of user set in Slack. Server time zone is returned if it cannot be loaded.
+func userLocation(ctx context.Context, slackRepo *repositories.SlackRepository, userID string) *time.Location {
+	tz, err := slackRepo.FetchUserTimeZone(userID)
+	if err == nil {
+		var loc *time.Location
+		if loc, err = time.LoadLocation(tz); err == nil {
+			return loc
+		}
+	}
+
+	slog.WarnContext(ctx, "Failed loading time zone of user, so server time zone is used", "tz", tz, "error", err)
+	return time.Local
+}
+
+// writeHistory writes all records of user to w in format.
+func writeHistory(w io.Writer, repo interfaces.HydrationRepository, userName string, format string, loc *time.Location) error {
+	writer, err := export.NewWriter(format, w, loc)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.EachHydration(userName, writer.Write); err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// importRequest is value of buttons of import report, which identifies shared file and mapping of its columns.
+type importRequest struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name"`
+	Mapping  string `json:"mapping"`
+}
+
+// Limits of import report, as Slack rejects longer section text and button value.
+const (
+	maxImportInvalidRows = 10
+	maxImportReportRunes = 2900
+	maxButtonValueLength = 2000
 )
 
-func readConfig(configsDirPath string) (config.Config, error) {
-	config := config.Config{}
+// mappingReplacer restores text of message which Slack escaped or formatted into mapping of columns.
+var mappingReplacer = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "“", `"`, "”", `"`)
+
+// HandleFileShare reports what changes if CSV or JSON file shared in DM with bot is imported, with button which imports it.
+// Text of message is read as mapping of columns.
+func HandleFileShare(c echo.Context, appConfig config.Config, configsDirPath string, event slack.Event) error {
+	if len(event.Files) == 0 || len(event.User) == 0 {
+		return c.String(http.StatusOK, "")
+	}
+
+	userID := event.User
+	channel := event.Channel
+	file := event.Files[0]
+	request := importRequest{
+		FileID:   file.ID,
+		FileName: file.Name,
+		Mapping:  mappingReplacer.Replace(event.Text),
+	}
+
+	background.Go(c.Request().Context(), jobs.Describe("read import file", "user_id", userID, "file_id", file.ID, "file_name", file.Name), func(ctx context.Context) {
+		userName, err := slackRepo.WithContext(ctx).FetchUserName(userID)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		locale := userLocale(ctx, userName, userID, "")
+		slackRepo := slackRepo.ForLocale(locale).WithContext(ctx)
+
+		text, err := reportImport(ctx, slackRepo, channel, userName, locale, file, request)
+		if err != nil {
+			logError(ctx, err)
+			text = i18n.T(locale, "import.failed", file.Name, i18n.T(locale, "import.error"))
+		}
+
+		if len(text) > 0 {
+			if _, err := slackRepo.PostText(channel, text); err != nil {
+				logError(ctx, err)
+			}
+		}
+	})
+
+	return c.String(http.StatusOK, "")
+}
+
+// reportImport posts report of file to channel with import buttons if it has new records.
+// Otherwise it returns text which tells user why file is not imported.
+func reportImport(ctx context.Context, slackRepo *repositories.SlackRepository, channel string, userName string, locale string, file slack.File, request importRequest) (string, error) {
+	rows, problem, err := readImport(ctx, slackRepo, userName, locale, file, request.Mapping)
+	if err != nil || len(problem) > 0 {
+		return problem, err
+	}
+
+	summary := importer.Summarize(rows)
+	text := importReport(locale, file.Name, rows, summary)
+	if summary.New == 0 {
+		return text + "\n" + i18n.T(locale, "import.nothing"), nil
+	}
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	if len(requestJSON) > maxButtonValueLength {
+		return i18n.T(locale, "import.failed", file.Name, i18n.T(locale, "import.mapping_too_long")), nil
+	}
+
+	resp, err := slackRepo.PostImportReport(channel, text, string(requestJSON))
+	if err != nil {
+		return "", err
+	}
+
+	var result slack.Response
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return "", err
+	}
+	if !result.Ok {
+		return "", fmt.Errorf("Failed posting import report: %s", result.Error)
+	}
+
+	return "", nil
+}
+
+// readImport downloads file and returns its rows checked for duplicates of records of user.
+// Problem is returned instead of rows if file cannot be imported for reason which user can fix.
+func readImport(ctx context.Context, slackRepo *repositories.SlackRepository, userName string, locale string, file slack.File, mappingSpec string) ([]importer.Row, string, error) {
+	format := importer.FormatOf(file.Name)
+	if len(format) == 0 {
+		return nil, i18n.T(locale, "import.unknown_format", file.Name), nil
+	}
+
+	mapping, err := importer.ParseMapping(mappingSpec)
+	if err != nil {
+		return nil, i18n.T(locale, "import.failed", file.Name, err.Error()), nil
+	}
+
+	content, err := slackRepo.DownloadFile(file, importer.MaxFileSize)
+	if errors.Is(err, slack.ErrFileTooLarge) {
+		return nil, i18n.T(locale, "import.too_large", file.Name, importer.MaxFileSize>>20), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	loc := userLocation(ctx, slackRepo, file.User)
+
+	rows, err := importer.Read(bytes.NewReader(content), format, mapping, loc, locale, time.Now())
+	if err != nil {
+		return nil, i18n.T(locale, "import.failed", file.Name, err.Error()), nil
+	}
+
+	if err := importer.Prepare(repo.WithContext(ctx), rows, userName); err != nil {
+		return nil, "", err
+	}
+
+	return rows, "", nil
+}
+
+// importReport returns summary of rows with reasons of first invalid rows.
+func importReport(locale string, fileName string, rows []importer.Row, summary importer.Summary) string {
+	lines := []string{i18n.T(locale, "import.report", fileName, summary.New, summary.Duplicates, summary.Invalid)}
+
+	for _, row := range rows {
+		if row.Status != importer.StatusInvalid {
+			continue
+		}
+		if len(lines) > maxImportInvalidRows {
+			lines = append(lines, i18n.T(locale, "import.more_invalid", summary.Invalid-maxImportInvalidRows))
+			break
+		}
+		lines = append(lines, i18n.T(locale, "import.invalid_row", row.Number, row.Problem))
+	}
+
+	text := []rune(strings.Join(lines, "\n"))
+	if len(text) > maxImportReportRunes {
+		text = append(text[:maxImportReportRunes-1], '…')
+	}
+	return string(text)
+}
+
+// HandleImportConfirm imports file of report whose import button is pressed, and replaces report with result.
+// File is read again, so records added after report are not duplicated.
+func HandleImportConfirm(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	var request importRequest
+	if err := json.Unmarshal([]byte(payload.Actions[0].Value), &request); err != nil {
+		slog.WarnContext(c.Request().Context(), "Invalid import request", "value", payload.Actions[0].Value)
+		return c.String(http.StatusBadRequest, "Error")
+	}
+
+	userID := payload.User.ID
+	userName := router.UserName(c, payload)
+	locale := router.Locale(c)
+	responseURL := payload.ResponseURL
+
+	background.Go(c.Request().Context(), jobs.Describe("import records", "user", userName, "file_id", request.FileID, "file_name", request.FileName), func(ctx context.Context) {
+		slackRepo := slackRepo.ForLocale(locale).WithContext(ctx)
+
+		text, err := importFile(ctx, slackRepo, userID, userName, locale, request)
+		if err != nil {
+			logError(ctx, err)
+			text = i18n.T(locale, "import.failed", request.FileName, i18n.T(locale, "import.error"))
+		}
+
+		if _, err := slackRepo.Respond(responseURL, slack.EscapeMrkdwn(text), true); err != nil {
+			logError(ctx, err)
+		}
+
+		refreshHome(ctx, appConfig, configsDirPath, userID, userName)
+	})
+
+	return c.String(http.StatusOK, "")
+}
+
+// importFile adds new records of file shared by user and returns result shown to user.
+func importFile(ctx context.Context, slackRepo *repositories.SlackRepository, userID string, userName string, locale string, request importRequest) (string, error) {
+	file, err := slackRepo.FetchFile(request.FileID)
+	if err != nil {
+		return "", err
+	}
+	if file.User != userID {
+		return "", fmt.Errorf("File %s was not shared by user %s", file.ID, userID)
+	}
+
+	rows, problem, err := readImport(ctx, slackRepo, userName, locale, file, request.Mapping)
+	if err != nil || len(problem) > 0 {
+		return problem, err
+	}
+
+	added, err := importer.Apply(repo.WithContext(ctx), rows, models.Actor{Username: userName, Source: models.SourceImport})
+	if err != nil {
+		return "", fmt.Errorf("Failed importing after adding %d records: %w", added, err)
+	}
+
+	return i18n.T(locale, "import.done", added, file.Name), nil
+}
+
+// HandleImportCancel replaces import report with message that import was cancelled.
+func HandleImportCancel(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	var request importRequest
+	if err := json.Unmarshal([]byte(payload.Actions[0].Value), &request); err != nil {
+		slog.WarnContext(c.Request().Context(), "Invalid import request", "value", payload.Actions[0].Value)
+		return c.String(http.StatusBadRequest, "Error")
+	}
+
+	locale := router.Locale(c)
+	responseURL := payload.ResponseURL
+
+	background.Go(c.Request().Context(), jobs.Describe("cancel import", "file_id", request.FileID), func(ctx context.Context) {
+		_, err := slackRepo.WithContext(ctx).Respond(responseURL, slack.EscapeMrkdwn(i18n.T(locale, "import.cancelled", request.FileName)), true)
+		if err != nil {
+			logError(ctx, err)
+		}
+	})
+
+	return c.String(http.StatusOK, "")
+}
+
+// HandleDeleteMyDataCommand shows button which deletes all data of user after confirmation.
+func HandleDeleteMyDataCommand(c echo.Context, appConfig config.Config, configsDirPath string, locale string) error {
+	slackRepo := slackRepo.ForLocale(locale).WithContext(c.Request().Context())
+
+	text := i18n.T(locale, "data.delete_prompt")
+	blocks, err := slackRepo.RenderDataDeletion(text, "hydration__delete_my_data", c.FormValue("user_id"), i18n.T(locale, "data.delete_confirm_text"))
+	if err != nil {
+		logError(c.Request().Context(), err)
+		return c.String(http.StatusInternalServerError, "Error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"response_type": "ephemeral",
+		"text":          text,
+		"blocks":        blocks,
+	})
+}
+
+// HandleDeleteMyData deletes all data of user who confirmed deletion, and tells result in place of message
+// or in DM if button is in App Home.
+func HandleDeleteMyData(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	userID := payload.User.ID
+	userName := router.UserName(c, payload)
+	locale := router.Locale(c)
+	responseURL := payload.ResponseURL
+
+	background.Go(c.Request().Context(), jobs.Describe("delete user data", "user", userName), func(ctx context.Context) {
+		deleted, err := repo.WithContext(ctx).DeleteUserData(userName, models.Actor{Username: userName, Source: models.SourceButton})
+		text := i18n.T(locale, "data.deleted", deleted)
+		if err != nil {
+			logError(ctx, err)
+			text = i18n.T(locale, "data.delete_failed")
+		}
+
+		if err := respondOrDirectMessage(ctx, locale, responseURL, userID, text); err != nil {
+			logError(ctx, err)
+		}
+
+		refreshHome(ctx, appConfig, configsDirPath, userID, userName)
+	})
+
+	return c.String(http.StatusOK, "")
+}
+
+// HandlePurgeCommand shows button which deletes all data of user given in args after confirmation.
+// It is for admins to remove data of users who left workspace.
+func HandlePurgeCommand(c echo.Context, appConfig config.Config, configsDirPath string, locale string, args []string) error {
+	slackRepo := slackRepo.ForLocale(locale).WithContext(c.Request().Context())
+
+	if len(args) != 1 {
+		return c.JSON(http.StatusOK, map[string]string{
+			"response_type": "ephemeral",
+			"text":          i18n.T(locale, "command.purge_usage", c.FormValue("command")),
+		})
+	}
+
+	userName := strings.TrimPrefix(args[0], "@")
+	text := i18n.T(locale, "data.purge_prompt", userName)
+	blocks, err := slackRepo.RenderDataDeletion(text, "hydration__purge_user", userName, i18n.T(locale, "data.purge_confirm_text", userName))
+	if err != nil {
+		logError(c.Request().Context(), err)
+		return c.String(http.StatusInternalServerError, "Error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"response_type": "ephemeral",
+		"text":          text,
+		"blocks":        blocks,
+	})
+}
+
+// HandlePurgeUser deletes all data of user in value of button after admin confirmed deletion.
+// Admin is checked again as button may be pressed after admins are changed.
+func HandlePurgeUser(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	adminName := router.UserName(c, payload)
+	if !isAdmin(appConfig, payload.User.ID) {
+		slog.WarnContext(c.Request().Context(), "Purge by user who is not admin", "user_id", payload.User.ID)
+		return c.String(http.StatusForbidden, "Error")
+	}
+
+	userName := payload.Actions[0].Value
+	locale := router.Locale(c)
+	responseURL := payload.ResponseURL
+
+	background.Go(c.Request().Context(), jobs.Describe("purge user", "admin", adminName, "user", userName), func(ctx context.Context) {
+		deleted, err := repo.WithContext(ctx).DeleteUserData(userName, models.Actor{Username: adminName, Source: models.SourceButton})
+		text := i18n.T(locale, "data.purged", userName, deleted)
+		if err != nil {
+			logError(ctx, err)
+			text = i18n.T(locale, "data.delete_failed")
+		}
+
+		if _, err := slackRepo.WithContext(ctx).Respond(responseURL, slack.EscapeMrkdwn(text), true); err != nil {
+			logError(ctx, err)
+		}
+	})
+
+	return c.String(http.StatusOK, "")
+}
+
+// respondOrDirectMessage replaces message of response URL with text, or posts text to DM of user
+// if there is no response URL as for buttons in App Home.
+func respondOrDirectMessage(ctx context.Context, locale string, responseURL string, userID string, text string) error {
+	slackRepo := slackRepo.ForLocale(locale).WithContext(ctx)
+
+	if len(responseURL) > 0 {
+		_, err := slackRepo.Respond(responseURL, slack.EscapeMrkdwn(text), true)
+		return err
+	}
+
+	channel, err := slackRepo.OpenDirectMessage(userID)
+	if err != nil {
+		return err
+	}
+	_, err = slackRepo.PostText(channel, text)
+	return err
+}
+
+// HandleAppHomeOpened publishes App Home dashboard.
+func HandleAppHomeOpened(c echo.Context, appConfig config.Config, configsDirPath string, event slack.Event) error {
+	slackRepo := slackRepo.WithContext(c.Request().Context())
+	userID := event.User
+
+	if event.Tab != "home" || len(userID) == 0 {
+		return c.String(http.StatusOK, "")
+	}
+
+	background.Go(c.Request().Context(), jobs.Describe("publish home", "user_id", userID), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		userName, err := slackRepo.FetchUserName(userID)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		refreshHome(ctx, appConfig, configsDirPath, userID, userName)
+	})
+
+	return c.String(http.StatusOK, "")
+}
+
+// refreshHome republishes App Home dashboard of user.
+func refreshHome(ctx context.Context, appConfig config.Config, configsDirPath string, userID string, userName string) {
+	if len(userID) == 0 {
+		return
+	}
+	repo := repo.WithContext(ctx)
+
+	dailyAmount, err := repo.FetchDailyAmount(userName)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+
+	settings, err := repo.FetchUserSettings(userName)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+
+	summaries, err := repo.FetchDailySummaries(userName, 7)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+
+	recentHydrations, err := repo.FetchRecent(userName, 10)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+
+	favorites, err := favoriteDrinks(ctx, userName, models.MaxFavoriteDrinks)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+
+	revision, err := repo.FetchLastRevision(userName)
+	if err != nil {
+		logError(ctx, err)
+		return
+	}
+	if revision.Age > undoWindow {
+		revision = models.HydrationRevision{}
+	}
 
-	configFilePath := filepath.Join(configsDirPath, "config.json")
-	if !file.FileExists(configFilePath) {
-		return config, fmt.Errorf("Config file does not exist: %s", configFilePath)
+	dashboard := models.HydrationDashboard{
+		DailyAmount:      dailyAmount,
+		DailyGoal:        dailyGoal(appConfig, settings),
+		DailySummaries:   summaries,
+		RecentHydrations: recentHydrations,
+		FavoriteDrinks:   favorites,
+		UndoRevision:     revision,
 	}
 
-	jsonContent, err := ioutil.ReadFile(configFilePath)
+	slackRepo := slackRepo.ForLocale(settingsLocale(ctx, settings, userID, "")).WithContext(ctx)
+	_, err = slackRepo.PublishHome(userID, dashboard, userLocation(ctx, slackRepo, userID))
 	if err != nil {
-		return config, err
+		logError(ctx, err)
 	}
+}
+
+// slackLocales caches locale set in Slack keyed by user ID.
+var slackLocales sync.Map
 
-	if err := json.Unmarshal(jsonContent, &config); err != nil {
-		return config, err
+// userLocale returns locale of user from settings, falling back to locale set in Slack and default locale.
+func userLocale(ctx context.Context, userName string, userID string, slackLocale string) string {
+	settings, err := repo.WithContext(ctx).FetchUserSettings(userName)
+	if err != nil {
+		settings = models.UserSettings{}
 	}
 
-	return config, nil
+	return settingsLocale(ctx, settings, userID, slackLocale)
 }
 
-func main() {
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: slack_bot_hydration [flags]\n")
-		flag.PrintDefaults()
+// settingsLocale returns locale of settings, falling back to locale set in Slack and default locale.
+// Locale set in Slack is fetched with users.info if slackLocale is empty.
+func settingsLocale(ctx context.Context, settings models.UserSettings, userID string, slackLocale string) string {
+	if i18n.IsSupported(settings.Locale) {
+		return settings.Locale
 	}
 
-	pConfigsDirPath := flag.String("c", "", "Configs dir path")
-	flag.Parse()
+	if len(slackLocale) == 0 && len(userID) > 0 {
+		if cached, ok := slackLocales.Load(userID); ok {
+			slackLocale, _ = cached.(string)
+		} else if fetched, err := slackRepo.WithContext(ctx).FetchUserLocale(userID); err == nil {
+			slackLocales.Store(userID, fetched)
+			slackLocale = fetched
+		}
+	}
 
-	if !file.FileExists(*pConfigsDirPath) {
-		fmt.Println("Config dir path does not exist")
-		return
+	if locale := i18n.Normalize(slackLocale); len(locale) > 0 {
+		return locale
 	}
 
-	configsDirPath := *pConfigsDirPath
+	return i18n.DefaultLocale
+}
+
+// favoriteDrinks returns pinned drinks followed by most frequent drinks of user.
+func favoriteDrinks(ctx context.Context, userName string, limit int) ([]models.FavoriteDrink, error) {
+	repo := repo.WithContext(ctx)
 
-	var err error
-	appConfig, err := readConfig(configsDirPath)
+	pinnedDrinks, err := repo.FetchPinnedDrinks(userName)
 	if err != nil {
-		panic(err)
+		return pinnedDrinks, err
 	}
 
-	SetUp(appConfig)
-	defer TearDown()
+	frequentDrinks, err := repo.FetchFrequentDrinks(userName, limit)
+	if err != nil {
+		return pinnedDrinks, err
+	}
 
-	e := echo.New()
+	return models.MergeFavoriteDrinks(pinnedDrinks, frequentDrinks, limit), nil
+}
 
-	appLogFile, err := os.OpenFile(filepath.Join(appConfig.LogDirPath, "app.log"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
-	if err != nil {
-		panic(err)
+// dailyGoal returns daily goal of user falling back to configured default.
+func dailyGoal(appConfig config.Config, settings models.UserSettings) int64 {
+	if settings.DailyGoal > 0 {
+		return settings.DailyGoal
 	}
-	e.Logger.SetLevel(log.INFO)
-	e.Logger.SetOutput(appLogFile)
 
-	accessLogFile, err := os.OpenFile(filepath.Join(appConfig.LogDirPath, "access.log"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
-	if err != nil {
-		panic(err)
+	if appConfig.DailyGoal > 0 {
+		return appConfig.DailyGoal
 	}
 
-	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
-		Output: accessLogFile,
-	}))
+	return 2000
+}
 
-	e.Use(middleware.Recover())
+// HandleOpenSettingsForm opens user settings modal.
+func HandleOpenSettingsForm(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	slackRepo := slackRepo.ForLocale(router.Locale(c)).WithContext(c.Request().Context())
+	triggerID := payload.TriggerID
+	userName := router.UserName(c, payload)
 
-	e.POST("/", func(c echo.Context) error {
-		return gateway(c, appConfig, configsDirPath)
+	background.Go(c.Request().Context(), jobs.Describe("open settings form", "user", userName), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		repo := repo.WithContext(ctx)
+		settings, err := repo.FetchUserSettings(userName)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+		settings.DailyGoal = dailyGoal(appConfig, settings)
+
+		_, err = slackRepo.OpenSettingsView(triggerID, settings)
+		if err != nil {
+			logError(ctx, err)
+		}
 	})
 
-	e.Logger.Fatal(e.Start(appConfig.ServerHost))
+	return c.String(http.StatusOK, "")
 }
 
-// SetUp initializes App
-func SetUp(appConfig config.Config) error {
-	if appConfig.Db.Client == "postgresql" {
-		repo = &repositories.HydrationPgRepository{}
+// HandleSettingsFormSubmission saves user settings.
+func HandleSettingsFormSubmission(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.ViewSubmissionPayload) error {
+	state := payload.View.State
+	userName := router.UserName(c, payload)
+	userID := payload.User.ID
+
+	form := validation.SettingsForm{
+		DailyGoal:      state.Value(validation.BlockDailyGoal, validation.BlockDailyGoal).Value,
+		PostMode:       state.Value(validation.BlockPostMode, validation.BlockPostMode).SelectedValue(),
+		PostChannel:    state.Value(validation.BlockPostChannel, validation.BlockPostChannel).SelectedConversation,
+		SelectedLocale: state.Value(validation.BlockLocale, validation.BlockLocale).SelectedValue(),
+		Locale:         router.Locale(c),
 	}
 
-	err := repo.Connect(appConfig.Db)
-	if err != nil {
-		fmt.Println("Failed connecting db")
-		return err
+	settings, validationErrors := form.Validate()
+	if len(validationErrors) > 0 {
+		return c.JSON(http.StatusOK, validationErrors.Response())
 	}
 
-	return nil
+	background.Go(c.Request().Context(), jobs.Describe("save settings", "user", userName), func(ctx context.Context) {
+		repo := repo.WithContext(ctx)
+		settings.Username = userName
+		settings.UserID = userID
+
+		err := repo.SaveUserSettings(settings)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		refreshHome(ctx, appConfig, configsDirPath, userID, userName)
+	})
+
+	return c.String(http.StatusOK, "")
 }
 
-// TearDown destructs App
-func TearDown() {
-	repo.Close()
+// HandleOpenFavoritesDialog opens modal with quick logging buttons of favorite drinks.
+func HandleOpenFavoritesDialog(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.MessageActionPayload) error {
+	slackRepo := slackRepo.ForLocale(router.Locale(c)).WithContext(c.Request().Context())
+	triggerID := payload.TriggerID
+	userName := router.UserName(c, payload)
+
+	background.Go(c.Request().Context(), jobs.Describe("open favorites", "user", userName), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		favorites, err := favoriteDrinks(ctx, userName, models.MaxFavoriteDrinks)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		if len(favorites) == 0 {
+			_, err = slackRepo.ShowAlert(triggerID, i18n.T(slackRepo.Locale, "favorites.title"), i18n.T(slackRepo.Locale, "favorites.empty"))
+		} else {
+			_, err = slackRepo.OpenFavoritesView(triggerID, favorites)
+		}
+		if err != nil {
+			logError(ctx, err)
+		}
+	})
+
+	return c.String(http.StatusOK, "")
 }
 
-func gateway(c echo.Context, appConfig config.Config, configsDirPath string) error {
-	payloadJSON := c.FormValue("payload")
-	c.Echo().Logger.Info(payloadJSON)
+// HandleQuickLog adds hydration of favorite drink.
+func HandleQuickLog(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	slackRepo := slackRepo.ForLocale(router.Locale(c)).WithContext(c.Request().Context())
+	key := payload.Actions[0].Value
+	userName := router.UserName(c, payload)
+	userID := payload.User.ID
+	teamID := payload.Team.ID
+	destination := routing.Destination{
+		OriginChannel: payload.Channel.ID,
+		UserID:        userID,
+	}
 
-	var payload interface{}
-	err := json.Unmarshal([]byte(payloadJSON), &payload)
-	if err != nil {
-		c.Echo().Logger.Error(err)
-		return c.String(http.StatusInternalServerError, "Error")
+	favorite, ok := models.ParseFavoriteDrinkKey(key)
+	if !ok {
+		slog.WarnContext(c.Request().Context(), "Invalid favorite drink", "key", key)
+		return c.String(http.StatusBadRequest, "Error")
 	}
 
-	params, _ := json.Marshal(payload)
-	c.Echo().Logger.Info(string(params))
-	//prettyParams, _ := json.MarshalIndent(payload, "", "    ")
-	//fmt.Println(string(prettyParams))
+	now := time.Now()
+	form := validation.HydrationForm{
+		Drink:     favorite.Drink,
+		Amount:    strconv.FormatInt(favorite.Amount, 10),
+		DrankDate: now.Format(validation.DateFormat),
+		DrankTime: now.Format(validation.TimeFormat),
+	}
 
-	iRequestType, err := jsonpointer.Get(payload, "/type")
-	if err != nil {
-		c.Echo().Logger.Error(err)
-		return c.String(http.StatusInternalServerError, "Error")
+	hydration, validationErrors := form.Validate(now)
+	if len(validationErrors) > 0 {
+		slog.WarnContext(c.Request().Context(), "Invalid favorite drink", "key", key)
+		return c.String(http.StatusBadRequest, "Error")
 	}
-	requestType := iRequestType.(string)
-
-	var iCallbackID interface{}
-	switch requestType {
-	case "shortcut":
-		iCallbackID, _ = jsonpointer.Get(payload, "/callback_id")
-	case "view_submission":
-		iCallbackID, _ = jsonpointer.Get(payload, "/view/callback_id")
-	case "block_actions":
-		iCallbackID, _ = jsonpointer.Get(payload, "/actions/0/action_id")
-	}
-
-	callbackID := iCallbackID.(string)
-	if len(callbackID) > 0 {
-		switch callbackID {
-		case "hydration__record_drink":
-			return HandleOpenHydrationForm(c, appConfig, configsDirPath, payload)
-		case "hydration__record_form":
-			return HandleHydrationFormAddSubmission(c, appConfig, configsDirPath, payload)
-		case "hydration__update_drink":
-			return HandleOpenHydrationUpdateForm(c, appConfig, configsDirPath, payload)
-		case "hydration__update_form":
-			return HandleHydrationFormUpdateSubmission(c, appConfig, configsDirPath, payload)
-		case "hydration__delete_drink":
-			return HandleHydrationDelete(c, appConfig, configsDirPath, payload)
-		case "hydration__repeat_drink":
-			return HandleHydrationRepeat(c, appConfig, configsDirPath, payload)
-		default:
-			c.Echo().Logger.Warn("Unrecognized callbackID:", callbackID)
+
+	background.Go(c.Request().Context(), jobs.Describe("quick log", "user", userName, "drink", favorite.Drink, "amount", favorite.Amount), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		repo := repo.WithContext(ctx)
+		hydration.Username = userName
+		hydration.DrankAt = now
+		hydration.UpdatedAt = now
+
+		hydrationID, err := repo.Add(hydration, models.Actor{Username: userName, Source: models.SourceButton})
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+		hydration.ID = hydrationID
+
+		refreshHome(ctx, appConfig, configsDirPath, userID, userName)
+
+		dailyAmount, err := repo.FetchDailyAmount(userName)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		channel, err := resultChannel(ctx, appConfig, teamID, userName, destination)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		resp, err := slackRepo.PostHydrationAddResult(userName, channel, hydration, dailyAmount, userLocation(ctx, slackRepo, userID))
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		message, err := saveResultMessage(ctx, hydration.ID, resp)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		err = postUndoMessage(ctx, slackRepo, message.Channel, userID, hydration.ID)
+		if err != nil {
+			logError(ctx, err)
+		}
+	})
+
+	return c.String(http.StatusOK, "")
+}
+
+// HandlePinDrink pins drink of selected hydration to favorites.
+func HandlePinDrink(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	slackRepo := slackRepo.ForLocale(router.Locale(c)).WithContext(c.Request().Context())
+	hydrationID, ok := actionHydrationID(c.Request().Context(), payload)
+	if !ok {
+		return c.String(http.StatusBadRequest, "Error")
+	}
+
+	userName := router.UserName(c, payload)
+	userID := payload.User.ID
+
+	background.Go(c.Request().Context(), jobs.Describe("pin drink", "user", userName, "hydration_id", hydrationID), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		repo := repo.WithContext(ctx)
+		hydration, err := repo.FetchOne(hydrationID)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		if hydration.Username != userName {
+			_, err = slackRepo.ShowAlert(payload.TriggerID, i18n.T(slackRepo.Locale, "alert.pin_forbidden.title"), i18n.T(slackRepo.Locale, "alert.pin_forbidden.text"))
+			if err != nil {
+				logError(ctx, err)
+			}
+			return
+		}
+
+		favorite := models.FavoriteDrink{
+			Drink:  hydration.Drink,
+			Amount: hydration.Amount,
+		}
+
+		pinnedDrinks, err := repo.FetchPinnedDrinks(userName)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		// pins beyond buttons shown would be hidden, so drink is pinned only while there is room.
+		isPinned := false
+		for _, pinnedDrink := range pinnedDrinks {
+			if pinnedDrink.Key() == favorite.Key() {
+				isPinned = true
+				break
+			}
+		}
+		if !isPinned && len(pinnedDrinks) >= models.MaxFavoriteDrinks {
+			_, err = slackRepo.ShowAlert(payload.TriggerID, i18n.T(slackRepo.Locale, "alert.pin_full.title"), i18n.T(slackRepo.Locale, "alert.pin_full.text", models.MaxFavoriteDrinks))
+			if err != nil {
+				logError(ctx, err)
+			}
+			return
+		}
+
+		err = repo.PinDrink(userName, favorite)
+		if err != nil {
+			logError(ctx, err)
+			return
 		}
+
+		refreshHome(ctx, appConfig, configsDirPath, userID, userName)
+	})
+
+	return c.String(http.StatusOK, "")
+}
+
+// HandleUnpinDrink removes drink from favorites.
+func HandleUnpinDrink(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	key := payload.Actions[0].Value
+	userName := router.UserName(c, payload)
+	userID := payload.User.ID
+
+	favorite, ok := models.ParseFavoriteDrinkKey(key)
+	if !ok {
+		slog.WarnContext(c.Request().Context(), "Invalid favorite drink", "key", key)
+		return c.String(http.StatusBadRequest, "Error")
 	}
 
-	return c.String(http.StatusForbidden, "Error")
+	background.Go(c.Request().Context(), jobs.Describe("unpin drink", "user", userName, "drink", favorite.Drink, "amount", favorite.Amount), func(ctx context.Context) {
+		repo := repo.WithContext(ctx)
+		err := repo.UnpinDrink(userName, favorite)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		refreshHome(ctx, appConfig, configsDirPath, userID, userName)
+	})
+
+	return c.String(http.StatusOK, "")
 }
 
 // HandleOpenHydrationForm opens hydration record form modal.
-func HandleOpenHydrationForm(c echo.Context, appConfig config.Config, configsDirPath string, payload interface{}) error {
+func HandleOpenHydrationForm(c echo.Context, appConfig config.Config, configsDirPath string, interaction slack.Interaction) error {
+	slackRepo := slackRepo.ForLocale(router.Locale(c)).WithContext(c.Request().Context())
+	triggerID := interaction.Common().TriggerID
+	originChannel := interaction.Common().Channel.ID
+	userID := interaction.Common().User.ID
 
 	// create goroutine for building modal and requesting view.open to Slack.
-	go func() {
-		slackRepo := &repositories.SlackRepository{
-			Token:        appConfig.Slack.Token,
-			ViewsDirPath: filepath.Join(configsDirPath, "views"),
+	background.Go(c.Request().Context(), jobs.Describe("open record form", "user_id", userID), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		loc := userLocation(ctx, slackRepo, userID)
+
+		viewMetadata, err := metadata.Encode(appConfig.Slack.MetadataSecret, metadata.Metadata{
+			Channel:  originChannel,
+			TimeZone: loc.String(),
+		})
+		if err != nil {
+			logError(ctx, err)
+			return
 		}
 
-		triggerID, _ := jsonpointer.Get(payload, "/trigger_id")
-
-		_, err := slackRepo.OpenHydrationAddView(triggerID.(string))
+		_, err = slackRepo.OpenHydrationAddView(triggerID, viewMetadata, loc)
 		if err != nil {
-			c.Echo().Logger.Error(err)
+			logError(ctx, err)
 		}
-	}()
+	})
 
 	return c.String(http.StatusOK, "Ok")
 }
 
 // HandleHydrationFormAddSubmission saves hydration and posts result message.
-func HandleHydrationFormAddSubmission(c echo.Context, appConfig config.Config, configsDirPath string, payload interface{}) error {
-
-	iDrink, _ := jsonpointer.Get(payload, "/view/state/values/drink/drink/value")
-	iAmount, _ := jsonpointer.Get(payload, "/view/state/values/amount/amount/selected_option/value")
-	iUserName, _ := jsonpointer.Get(payload, "/user/username")
+func HandleHydrationFormAddSubmission(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.ViewSubmissionPayload) error {
+	slackRepo := slackRepo.ForLocale(router.Locale(c)).WithContext(c.Request().Context())
+	userName := router.UserName(c, payload)
+	userID := payload.User.ID
+	teamID := payload.Team.ID
+	selectedChannel := payload.View.State.Value("channel", "channel").SelectedConversation
+
+	viewMetadata, err := metadata.Decode(appConfig.Slack.MetadataSecret, payload.View.PrivateMetadata)
+	if err != nil {
+		return rejectMetadata(c, appConfig, configsDirPath, err)
+	}
 
-	drink, _ := iDrink.(string)
-	amount, _ := iAmount.(string)
-	userName, _ := iUserName.(string)
+	destination := routing.Destination{
+		SelectedChannel: selectedChannel,
+		OriginChannel:   viewMetadata.Channel,
+		UserID:          userID,
+	}
 
-	intAmount, _ := strconv.ParseInt(amount, 10, 64)
+	now := time.Now()
+	hydration, validationErrors := readHydrationForm(payload.View.State, router.Locale(c), viewMetadata.Location()).Validate(now)
+	if len(validationErrors) > 0 {
+		return c.JSON(http.StatusOK, validationErrors.Response())
+	}
 
-	go func() {
-		hydration := models.Hydration{
-			Username: userName,
-			Drink:    drink,
-			Amount:   intAmount,
-			Modified: time.Now(),
-		}
+	background.Go(c.Request().Context(), jobs.Describe("add hydration", "user", userName, "drink", hydration.Drink, "amount", hydration.Amount), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		repo := repo.WithContext(ctx)
+		hydration.Username = userName
+		hydration.UpdatedAt = now
 
-		hydrationID, err := repo.Add(hydration)
+		hydrationID, err := repo.Add(hydration, models.Actor{Username: userName, Source: models.SourceModal})
 		if err != nil {
-			c.Echo().Logger.Error(err)
+			logError(ctx, err)
 			return
 		}
 		hydration.ID = hydrationID
 
+		refreshHome(ctx, appConfig, configsDirPath, userID, userName)
+
 		dailyAmount, err := repo.FetchDailyAmount(userName)
 		if err != nil {
-			c.Echo().Logger.Error(err)
+			logError(ctx, err)
 			return
 		}
 
-		slackRepo := &repositories.SlackRepository{
-			Token:        appConfig.Slack.Token,
-			ViewsDirPath: filepath.Join(configsDirPath, "views"),
+		channel, err := resultChannel(ctx, appConfig, teamID, userName, destination)
+		if err != nil {
+			logError(ctx, err)
+			return
 		}
 
-		_, err = slackRepo.PostHydrationAddResult(userName, "#general", hydration, dailyAmount)
+		resp, err := slackRepo.PostHydrationAddResult(userName, channel, hydration, dailyAmount, viewMetadata.Location())
 		if err != nil {
-			c.Echo().Logger.Error(err)
+			logError(ctx, err)
 			return
 		}
-	}()
+
+		message, err := saveResultMessage(ctx, hydration.ID, resp)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		err = postUndoMessage(ctx, slackRepo, message.Channel, userID, hydration.ID)
+		if err != nil {
+			logError(ctx, err)
+		}
+	})
 
 	return c.String(http.StatusOK, "")
 }
 
-// HandleOpenHydrationUpdateForm opens hydration edit form modal.
-func HandleOpenHydrationUpdateForm(c echo.Context, appConfig config.Config, configsDirPath string, payload interface{}) error {
-	iTriggerID, _ := jsonpointer.Get(payload, "/trigger_id")
-	triggerID := iTriggerID.(string)
+// rejectMetadata replaces modal with alert when private_metadata can not be trusted.
+func rejectMetadata(c echo.Context, appConfig config.Config, configsDirPath string, err error) error {
+	slackRepo := slackRepo.WithContext(c.Request().Context())
+	slog.WarnContext(c.Request().Context(), "Rejected view metadata", "error", err)
+	locale := router.Locale(c)
+
+	view, err := slackRepo.ForLocale(locale).RenderAlert(i18n.T(locale, "alert.invalid_form.title"), i18n.T(locale, "alert.invalid_form.text"))
+	if err != nil {
+		logError(c.Request().Context(), err)
+		return c.String(http.StatusInternalServerError, "Error")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"response_action": "update",
+		"view":            view,
+	})
+}
 
-	iUserName, _ := jsonpointer.Get(payload, "/user/username")
-	userName, _ := iUserName.(string)
+// readHydrationForm reads values submitted from record form whose date and time are in loc.
+func readHydrationForm(state slack.ViewState, locale string, loc *time.Location) validation.HydrationForm {
+	return validation.HydrationForm{
+		Locale:    locale,
+		Location:  loc,
+		Drink:     state.Value(validation.BlockDrink, validation.BlockDrink).Value,
+		Amount:    state.Value(validation.BlockAmount, validation.BlockAmount).Value,
+		DrankDate: state.Value(validation.BlockDrankDate, validation.BlockDrankDate).SelectedDate,
+		DrankTime: state.Value(validation.BlockDrankTime, validation.BlockDrankTime).SelectedTime,
+	}
+}
 
-	iHydrationID, _ := jsonpointer.Get(payload, "/actions/0/value")
-	sHydrationID, _ := iHydrationID.(string)
-	hydrationID, _ := strconv.ParseInt(sHydrationID, 10, 64)
+// actionHydrationID returns ID of hydration in value of clicked button.
+// Invalid value is logged and false is returned.
+func actionHydrationID(ctx context.Context, payload slack.BlockActionsPayload) (int64, bool) {
+	hydrationID, err := strconv.ParseInt(payload.Actions[0].Value, 10, 64)
+	if err != nil || hydrationID <= 0 {
+		slog.WarnContext(ctx, "Invalid hydration ID", "value", payload.Actions[0].Value)
+		return 0, false
+	}
+	return hydrationID, true
+}
 
-	iMessageTS, _ := jsonpointer.Get(payload, "/container/message_ts")
-	iChannel, _ := jsonpointer.Get(payload, "/container/channel_id")
-	messageTS, _ := iMessageTS.(string)
-	channel, _ := iChannel.(string)
+// HandleOpenHydrationUpdateForm opens hydration edit form modal.
+func HandleOpenHydrationUpdateForm(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	slackRepo := slackRepo.ForLocale(router.Locale(c)).WithContext(c.Request().Context())
+	triggerID := payload.TriggerID
+	userName := router.UserName(c, payload)
+	hydrationID, ok := actionHydrationID(c.Request().Context(), payload)
+	if !ok {
+		return c.String(http.StatusBadRequest, "Error")
+	}
+	messageTS := payload.Container.MessageTS
+	channel := payload.Container.ChannelID
 
-	go func() {
+	background.Go(c.Request().Context(), jobs.Describe("open update form", "user", userName, "hydration_id", hydrationID), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		repo := repo.WithContext(ctx)
 		hydration, err := repo.FetchOne(hydrationID)
 		if err != nil {
-			c.Echo().Logger.Error(err)
+			logError(ctx, err)
 			return
 		}
 
-		slackRepo := &repositories.SlackRepository{
-			Token:        appConfig.Slack.Token,
-			ViewsDirPath: filepath.Join(configsDirPath, "views"),
-		}
-
 		if hydration.Username == userName {
-			_, err = slackRepo.OpenHydrationUpdateView(triggerID, channel, messageTS, hydration)
+			loc := userLocation(ctx, slackRepo, payload.User.ID)
+
+			viewMetadata, err := metadata.Encode(appConfig.Slack.MetadataSecret, metadata.Metadata{
+				Channel:     channel,
+				MessageTS:   messageTS,
+				HydrationID: hydration.ID,
+				TimeZone:    loc.String(),
+			})
+			if err != nil {
+				logError(ctx, err)
+				return
+			}
+
+			_, err = slackRepo.OpenHydrationUpdateView(triggerID, viewMetadata, hydration, loc)
 			if err != nil {
-				c.Echo().Logger.Error(err)
+				logError(ctx, err)
 			}
 		} else {
-			_, err = slackRepo.ShowAlert(triggerID, "更新できません", "この記録を更新する権限がありません")
+			_, err = slackRepo.ShowAlert(triggerID, i18n.T(slackRepo.Locale, "alert.update_forbidden.title"), i18n.T(slackRepo.Locale, "alert.update_forbidden.text"))
 		}
-	}()
+	})
 
 	return c.String(http.StatusOK, "")
 }
 
 // HandleHydrationFormUpdateSubmission saves hydration and posts result message.
-func HandleHydrationFormUpdateSubmission(c echo.Context, appConfig config.Config, configsDirPath string, payload interface{}) error {
-
-	iMetadata, _ := jsonpointer.Get(payload, "/view/private_metadata")
-	iDrink, _ := jsonpointer.Get(payload, "/view/state/values/drink/drink/value")
-	iAmount, _ := jsonpointer.Get(payload, "/view/state/values/amount/amount/selected_option/value")
-	iUserName, _ := jsonpointer.Get(payload, "/user/username")
+func HandleHydrationFormUpdateSubmission(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.ViewSubmissionPayload) error {
+	slackRepo := slackRepo.ForLocale(router.Locale(c)).WithContext(c.Request().Context())
+	viewMetadata, err := metadata.Decode(appConfig.Slack.MetadataSecret, payload.View.PrivateMetadata)
+	if err == nil && viewMetadata.HydrationID <= 0 {
+		err = metadata.ErrMalformed
+	}
+	if err != nil {
+		return rejectMetadata(c, appConfig, configsDirPath, err)
+	}
 
-	metadata, _ := iMetadata.(string)
-	metadataList := strings.Split(metadata, "-")
+	channel := viewMetadata.Channel
+	messageTS := viewMetadata.MessageTS
+	hydrationID := viewMetadata.HydrationID
 
-	channel := metadataList[0]
-	messageTS := metadataList[1]
-	hydrationID, _ := strconv.ParseInt(metadataList[2], 10, 64)
+	userName := router.UserName(c, payload)
+	userID := payload.User.ID
 
-	drink, _ := iDrink.(string)
-	amount, _ := iAmount.(string)
-	userName, _ := iUserName.(string)
+	now := time.Now()
+	hydration, validationErrors := readHydrationForm(payload.View.State, router.Locale(c), viewMetadata.Location()).Validate(now)
+	if len(validationErrors) > 0 {
+		return c.JSON(http.StatusOK, validationErrors.Response())
+	}
 
-	intAmount, _ := strconv.ParseInt(amount, 10, 64)
+	background.Go(c.Request().Context(), jobs.Describe("update hydration", "user", userName, "hydration_id", hydrationID), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		repo := repo.WithContext(ctx)
+		exHydration, err := repo.FetchOne(hydrationID)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
 
-	go func() {
-		hydration := models.Hydration{
-			ID:       hydrationID,
-			Username: userName,
-			Drink:    drink,
-			Amount:   intAmount,
-			Modified: time.Now(),
+		// pickers only have minute precision, so keep original time unless user changed it.
+		// saved time is read back as UTC, so it is converted like times shown in form before comparing.
+		if hydration.DrankAt.Equal(export.InLocation(exHydration.DrankAt, viewMetadata.Location()).Truncate(time.Minute)) {
+			hydration.DrankAt = exHydration.DrankAt
 		}
 
-		err := repo.Update(hydration)
+		hydration.ID = hydrationID
+		hydration.Username = userName
+		hydration.UpdatedAt = now
+
+		err = repo.Update(hydration, models.Actor{Username: userName, Source: models.SourceModal})
 		if err != nil {
-			c.Echo().Logger.Error(err)
+			logError(ctx, err)
+			return
+		}
+
+		refreshHome(ctx, appConfig, configsDirPath, userID, userName)
+
+		// records updated from App Home use message saved with record.
+		if len(channel) == 0 {
+			channel = exHydration.Channel
+			messageTS = exHydration.MessageTS
+		}
+		if len(channel) == 0 {
 			return
 		}
 
 		dailyAmount, err := repo.FetchDailyAmount(userName)
 		if err != nil {
-			c.Echo().Logger.Error(err)
+			logError(ctx, err)
 			return
 		}
 
-		slackRepo := &repositories.SlackRepository{
-			Token:        appConfig.Slack.Token,
-			ViewsDirPath: filepath.Join(configsDirPath, "views"),
+		_, err = slackRepo.PostHydrationUpdateResult(userName, channel, messageTS, hydration, dailyAmount, viewMetadata.Location())
+		if err != nil {
+			logError(ctx, err)
+			return
 		}
 
-		_, err = slackRepo.PostHydrationUpdateResult(userName, channel, messageTS, hydration, dailyAmount)
+		err = postUndoMessage(ctx, slackRepo, channel, userID, hydration.ID)
 		if err != nil {
-			c.Echo().Logger.Error(err)
-			return
+			logError(ctx, err)
 		}
-	}()
+	})
 
 	return c.String(http.StatusOK, "")
 }
 
 // HandleHydrationDelete deletes hydration and deletes message.
-func HandleHydrationDelete(c echo.Context, appConfig config.Config, configsDirPath string, payload interface{}) error {
-
-	iHydrationID, _ := jsonpointer.Get(payload, "/actions/0/value")
-	sHydrationID, _ := iHydrationID.(string)
-	hydrationID, _ := strconv.ParseInt(sHydrationID, 10, 64)
-
-	iUserName, _ := jsonpointer.Get(payload, "/user/username")
-	userName, _ := iUserName.(string)
-
-	iMessageTS, _ := jsonpointer.Get(payload, "/container/message_ts")
-	messageTS, _ := iMessageTS.(string)
-
-	iChannel, _ := jsonpointer.Get(payload, "/container/channel_id")
-	channel, _ := iChannel.(string)
-
-	go func() {
+func HandleHydrationDelete(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	slackRepo := slackRepo.ForLocale(router.Locale(c)).WithContext(c.Request().Context())
+	hydrationID, ok := actionHydrationID(c.Request().Context(), payload)
+	if !ok {
+		return c.String(http.StatusBadRequest, "Error")
+	}
+	userName := router.UserName(c, payload)
+	userID := payload.User.ID
+	messageTS := payload.Container.MessageTS
+	channel := payload.Container.ChannelID
+
+	background.Go(c.Request().Context(), jobs.Describe("delete hydration", "user", userName, "hydration_id", hydrationID), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		repo := repo.WithContext(ctx)
 		hydration, err := repo.FetchOne(hydrationID)
 		if err != nil {
-			c.Echo().Logger.Error(err)
+			logError(ctx, err)
 			return
 		}
 
-		slackRepo := &repositories.SlackRepository{
-			Token:        appConfig.Slack.Token,
-			ViewsDirPath: filepath.Join(configsDirPath, "views"),
-		}
-
 		if hydration.Username == userName {
-			err = repo.Delete(hydration)
+			err = repo.Delete(hydration, models.Actor{Username: userName, Source: models.SourceButton})
 			if err != nil {
-				c.Echo().Logger.Error(err)
+				logError(ctx, err)
+				return
+			}
+
+			refreshHome(ctx, appConfig, configsDirPath, userID, userName)
+
+			// records deleted from App Home use message saved with record.
+			if len(channel) == 0 {
+				channel = hydration.Channel
+				messageTS = hydration.MessageTS
+			}
+			if len(channel) == 0 {
 				return
 			}
 
 			_, err = slackRepo.DeleteMessage(channel, messageTS)
 			if err != nil {
-				c.Echo().Logger.Error(err)
+				logError(ctx, err)
+			}
+
+			err = postUndoMessage(ctx, slackRepo, channel, userID, hydration.ID)
+			if err != nil {
+				logError(ctx, err)
 			}
 		} else {
-			iTriggerID, _ := jsonpointer.Get(payload, "/trigger_id")
-			triggerID := iTriggerID.(string)
-			_, err = slackRepo.ShowAlert(triggerID, "削除できません", "この記録を削除する権限がありません")
+			_, err = slackRepo.ShowAlert(payload.TriggerID, i18n.T(slackRepo.Locale, "alert.delete_forbidden.title"), i18n.T(slackRepo.Locale, "alert.delete_forbidden.text"))
 		}
 
-	}()
+	})
 
 	return c.String(http.StatusOK, "")
 }
 
 // HandleHydrationRepeat adds same hydration from selected message.
-func HandleHydrationRepeat(c echo.Context, appConfig config.Config, configsDirPath string, payload interface{}) error {
-
-	iHydrationID, _ := jsonpointer.Get(payload, "/actions/0/value")
-	sHydrationID, _ := iHydrationID.(string)
-	hydrationID, _ := strconv.ParseInt(sHydrationID, 10, 64)
-
-	iUserName, _ := jsonpointer.Get(payload, "/user/username")
-	userName, _ := iUserName.(string)
+func HandleHydrationRepeat(c echo.Context, appConfig config.Config, configsDirPath string, payload slack.BlockActionsPayload) error {
+	slackRepo := slackRepo.ForLocale(router.Locale(c)).WithContext(c.Request().Context())
+	hydrationID, ok := actionHydrationID(c.Request().Context(), payload)
+	if !ok {
+		return c.String(http.StatusBadRequest, "Error")
+	}
+	userName := router.UserName(c, payload)
+	userID := payload.User.ID
+	teamID := payload.Team.ID
+	destination := routing.Destination{
+		OriginChannel: payload.Channel.ID,
+		UserID:        userID,
+	}
 
-	go func() {
+	background.Go(c.Request().Context(), jobs.Describe("repeat hydration", "user", userName, "hydration_id", hydrationID), func(ctx context.Context) {
+		slackRepo := slackRepo.WithContext(ctx)
+		repo := repo.WithContext(ctx)
 		exHydration, err := repo.FetchOne(hydrationID)
 		if err != nil {
-			c.Echo().Logger.Error(err)
+			logError(ctx, err)
 			return
 		}
 
+		now := time.Now()
 		hydration := models.Hydration{
-			Username: userName,
-			Drink:    exHydration.Drink,
-			Amount:   exHydration.Amount,
-			Modified: time.Now(),
+			Username:  userName,
+			Drink:     exHydration.Drink,
+			Amount:    exHydration.Amount,
+			DrankAt:   now,
+			UpdatedAt: now,
 		}
 
-		hydrationID, err := repo.Add(hydration)
+		hydrationID, err := repo.Add(hydration, models.Actor{Username: userName, Source: models.SourceButton})
 		if err != nil {
-			c.Echo().Logger.Error(err)
+			logError(ctx, err)
 			return
 		}
 		hydration.ID = hydrationID
 
+		refreshHome(ctx, appConfig, configsDirPath, userID, userName)
+
 		dailyAmount, err := repo.FetchDailyAmount(userName)
 		if err != nil {
-			c.Echo().Logger.Error(err)
+			logError(ctx, err)
 			return
 		}
 
-		slackRepo := &repositories.SlackRepository{
-			Token:        appConfig.Slack.Token,
-			ViewsDirPath: filepath.Join(configsDirPath, "views"),
+		channel, err := resultChannel(ctx, appConfig, teamID, userName, destination)
+		if err != nil {
+			logError(ctx, err)
+			return
 		}
 
-		_, err = slackRepo.PostHydrationAddResult(userName, "#general", hydration, dailyAmount)
+		resp, err := slackRepo.PostHydrationAddResult(userName, channel, hydration, dailyAmount, userLocation(ctx, slackRepo, userID))
 		if err != nil {
-			c.Echo().Logger.Error(err)
+			logError(ctx, err)
 			return
 		}
 
-	}()
+		message, err := saveResultMessage(ctx, hydration.ID, resp)
+		if err != nil {
+			logError(ctx, err)
+			return
+		}
+
+		err = postUndoMessage(ctx, slackRepo, message.Channel, userID, hydration.ID)
+		if err != nil {
+			logError(ctx, err)
+		}
+
+	})
 
 	return c.String(http.StatusOK, "")
 }
@@ -1,87 +1,188 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"flag"
 	"fmt"
+	"io/fs"
 	"io/ioutil"
+	"log/slog"
 	"os"
 	"path/filepath"
 
-	"github.com/pirosuke/slack-bot-hydration/internal/file"
+	"github.com/golang/freetype/truetype"
+	"github.com/pirosuke/slack-bot-hydration/configs"
 	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/config"
-	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/interfaces"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/i18n"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/logging"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
 	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/repositories"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/routing"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/tracing"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/views"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/plotutil"
 	"gonum.org/v1/plot/vg"
 )
 
-func readConfig(configsDirPath string) (config.Config, error) {
-	config := config.Config{}
+// plotFontName is name which font for plot labels is registered as.
+const plotFontName = "PlotFont"
 
-	configFilePath := filepath.Join(configsDirPath, "config.json")
-	if !file.FileExists(configFilePath) {
-		return config, fmt.Errorf("Config file does not exist: %s", configFilePath)
+// japaneseGlyph is character which font must have to render Japanese labels.
+const japaneseGlyph = '水'
+
+// usePlotFont registers TrueType font at fontPath as default font of plots, or bundled font if fontPath is empty.
+// Font with CJK glyphs is needed to render Japanese labels.
+func usePlotFont(fontPath string) error {
+	var content []byte
+	var err error
+	if len(fontPath) > 0 {
+		content, err = ioutil.ReadFile(fontPath)
+	} else {
+		fontPath = configs.PlotFont
+		content, err = fs.ReadFile(configs.Fonts, fontPath)
+	}
+	if err != nil {
+		return err
 	}
 
-	jsonContent, err := ioutil.ReadFile(configFilePath)
+	font, err := truetype.Parse(content)
 	if err != nil {
-		return config, err
+		return fmt.Errorf("Failed parsing font %s: %v", fontPath, err)
 	}
+	if font.Index(japaneseGlyph) == 0 {
+		return fmt.Errorf("Font %s has no Japanese glyphs", fontPath)
+	}
+
+	vg.AddFont(plotFontName, font)
+	plot.DefaultFont = plotFontName
 
-	if err := json.Unmarshal(jsonContent, &config); err != nil {
-		return config, err
+	return nil
+}
+
+// postFavoriteButtons posts quick logging buttons of favorite drinks of user after weekly report.
+func postFavoriteButtons(ctx context.Context, repo repositories.InstrumentedRepository, slackRepo *repositories.SlackRepository, channel string, userName string) {
+	pinnedDrinks, err := repo.FetchPinnedDrinks(userName)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed fetching pinned drinks", "user", userName, "error", err)
+		return
 	}
 
-	return config, nil
+	frequentDrinks, err := repo.FetchFrequentDrinks(userName, models.MaxFavoriteDrinks)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed fetching frequent drinks", "user", userName, "error", err)
+		return
+	}
+
+	favorites := models.MergeFavoriteDrinks(pinnedDrinks, frequentDrinks, models.MaxFavoriteDrinks)
+	if len(favorites) == 0 {
+		return
+	}
+
+	if _, err := slackRepo.PostFavoriteButtons(channel, favorites); err != nil {
+		slog.ErrorContext(ctx, "Failed posting favorite drinks", "user", userName, "error", err)
+	}
 }
 
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: slack_bot_hydration [flags]\n")
+		fmt.Fprintf(os.Stderr, "Usage: slack_plot_hydration [flags]\n")
 		flag.PrintDefaults()
 	}
 
-	pConfigsDirPath := flag.String("c", "", "Configs dir path")
+	pConfigsDirPath := flag.String("c", "", "Configs dir path (config.json is not read if empty)")
 	flag.Parse()
 
-	if !file.FileExists(*pConfigsDirPath) {
-		fmt.Println("Config dir path does not exist")
-		return
+	configsDirPath := *pConfigsDirPath
+
+	appConfig, err := config.Load(configsDirPath, config.ForPlot)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	configsDirPath := *pConfigsDirPath
+	logOutput, err := logging.OpenOutput(appConfig.LogOutput)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer logOutput.Close()
 
-	var err error
-	appConfig, err := readConfig(configsDirPath)
+	logLevel, _ := logging.ParseLevel(appConfig.LogLevel)
+	slog.SetDefault(logging.New(logOutput, logLevel))
+
+	shutdownTracing, err := tracing.Setup(context.Background(), appConfig.Tracing, "slack_plot_hydration")
 	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
+	// ID of this run is logged with each record and Slack call as request ID.
+	ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+
+	// whole run is traced as one span so that queries and Slack calls of all users are grouped.
+	ctx, span := tracing.Start(ctx, "weekly report")
+	defer span.End()
+
+	if err := usePlotFont(appConfig.PlotFontPath); err != nil {
 		panic(err)
 	}
 
-	var repo interfaces.HydrationRepository
+	var repo repositories.InstrumentedRepository
 	if appConfig.Db.Client == "postgresql" {
-		repo = &repositories.HydrationPgRepository{}
+		repo = repositories.InstrumentedRepository{HydrationRepository: &repositories.HydrationPgRepository{}}.WithContext(ctx)
 	}
 
 	err = repo.Connect(appConfig.Db)
 	if err != nil {
-		fmt.Println("Failed connecting db")
+		slog.ErrorContext(ctx, "Failed connecting db", "error", err)
 		return
 	}
 
 	userList, err := repo.FetchWeeklyUsers()
 	if err != nil {
-		fmt.Println("Failed fetching user list")
+		slog.ErrorContext(ctx, "Failed fetching user list", "error", err)
 		return
 	}
 
+	defaultViews, err := fs.Sub(configs.Views, "views")
+	if err != nil {
+		panic(err)
+	}
+
+	viewSet, err := views.New(defaultViews, appConfig.ViewsDirPath)
+	if err != nil {
+		panic(err)
+	}
+
+	slackRepo := (&repositories.SlackRepository{
+		Token: appConfig.Slack.Token,
+		Views: viewSet,
+	}).WithContext(ctx)
+
 	for _, userName := range userList {
+		settings, err := repo.FetchUserSettings(userName)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed fetching settings", "user", userName, "error", err)
+			return
+		}
+
+		locale := settings.Locale
+		if !i18n.IsSupported(locale) {
+			locale = i18n.DefaultLocale
+			if len(settings.UserID) > 0 {
+				slackLocale, err := slackRepo.FetchUserLocale(settings.UserID)
+				if err == nil && len(i18n.Normalize(slackLocale)) > 0 {
+					locale = i18n.Normalize(slackLocale)
+				}
+			}
+		}
+
 		summaryList, err := repo.FetchWeeklySummary(userName)
 		if err != nil {
-			fmt.Println("Failed fetching summary for " + userName)
-			fmt.Println(err)
+			slog.ErrorContext(ctx, "Failed fetching summary", "user", userName, "error", err)
 			return
 		}
 
@@ -98,15 +199,15 @@ func main() {
 		}
 
 		p.Add(plotter.NewGrid())
-		p.Title.Text = "週間水分摂取量"
-		p.X.Label.Text = "曜日"
-		p.Y.Label.Text = "飲んだ量"
+		p.Title.Text = i18n.T(locale, "report.title")
+		p.X.Label.Text = i18n.T(locale, "report.x_label")
+		p.Y.Label.Text = i18n.T(locale, "report.y_label")
 
 		width := vg.Points(20)
 
 		bars, err := plotter.NewBarChart(amountList, width)
 		if err != nil {
-			fmt.Println("Failed creating bar chart for " + userName)
+			slog.ErrorContext(ctx, "Failed creating bar chart", "user", userName, "error", err)
 			return
 		}
 
@@ -119,20 +220,32 @@ func main() {
 
 		outputFileName := "plot.png"
 		outputPath := filepath.Join(appConfig.PlotOutputDirPath, outputFileName)
-		if p.Save(5*vg.Inch, 3*vg.Inch, outputPath); err != nil {
-			fmt.Println("Failed plot image output " + outputPath)
+		if err := p.Save(5*vg.Inch, 3*vg.Inch, outputPath); err != nil {
+			slog.ErrorContext(ctx, "Failed plot image output", "path", outputPath, "error", err)
 			return
 		}
 
-		slackRepo := &repositories.SlackRepository{
-			Token:        appConfig.Slack.Token,
-			ViewsDirPath: filepath.Join(configsDirPath, "views"),
+		channel := routing.ResultChannel(appConfig.Routing.ForWorkspace(""), settings, routing.Destination{})
+
+		// files can not be uploaded to user ID, so open DM channel with user.
+		isDirectMessage := channel == settings.UserID
+		if isDirectMessage {
+			channel, err = slackRepo.OpenDirectMessage(settings.UserID)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed opening DM", "user", userName, "error", err)
+				return
+			}
 		}
 
-		_, err = slackRepo.UploadFile("#general", outputFileName, "png", outputPath, "過去一週間の水分摂取量を報告します")
+		_, err = slackRepo.UploadFile(channel, outputFileName, "png", outputPath, i18n.T(locale, "report.caption"))
 		if err != nil {
-			fmt.Println("Failed posting file to slack.")
+			slog.ErrorContext(ctx, "Failed posting file to slack", "user", userName, "error", err)
 			return
 		}
+
+		// quick logging buttons are posted only to DM, as anyone in channel could press them.
+		if isDirectMessage {
+			postFavoriteButtons(ctx, repo, slackRepo.ForLocale(locale), channel, userName)
+		}
 	}
 }
@@ -0,0 +1,17 @@
+// Package configs holds default config files which are compiled into binaries.
+package configs
+
+import "embed"
+
+// Views holds default Block Kit view templates in views directory.
+//
+//go:embed views/*.json
+var Views embed.FS
+
+// Fonts holds fonts and their licenses in fonts directory.
+//
+//go:embed fonts
+var Fonts embed.FS
+
+// PlotFont is path of bundled font with Japanese glyphs in Fonts, which is used for chart labels.
+const PlotFont = "fonts/mplus-1p-regular.ttf"
@@ -0,0 +1,30 @@
+package configs
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/golang/freetype/truetype"
+)
+
+func TestPlotFontIsBundled(t *testing.T) {
+	content, err := fs.ReadFile(Fonts, PlotFont)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	font, err := truetype.Parse(content)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", PlotFont, err)
+	}
+	// characters of Japanese chart labels.
+	for _, glyph := range "週間水分摂取量曜日飲んだ" {
+		if font.Index(glyph) == 0 {
+			t.Errorf("%s has no glyph of %q", PlotFont, glyph)
+		}
+	}
+
+	if _, err := fs.Stat(Fonts, "fonts/LICENSE_MPLUS.txt"); err != nil {
+		t.Errorf("license of %s is not bundled: %v", PlotFont, err)
+	}
+}
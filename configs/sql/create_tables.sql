@@ -4,7 +4,79 @@ create table hydrations(
     ,username varchar(255) not null
     ,drink varchar(255) not null
     ,amount int not null
-    ,modified timestamp not null
+    ,drank_at timestamp not null
+    ,updated_at timestamp not null
+    ,channel varchar(255) not null default ''
+    ,message_ts varchar(255) not null default ''
+    ,deleted_at timestamp
     ,primary key (id)
 )
-;
\ No newline at end of file
+;
+create index hydrations_drank_at on hydrations(drank_at);
+
+drop table if exists hydration_revisions;
+create table hydration_revisions(
+    id serial
+    ,hydration_id int not null
+    ,username varchar(255) not null
+    ,operation varchar(16) not null
+    ,drink varchar(255) not null
+    ,amount int not null
+    ,drank_at timestamp not null
+    ,created_at timestamp not null
+    ,undone_at timestamp
+    ,primary key (id)
+)
+;
+create index hydration_revisions_username on hydration_revisions(username, id);
+create index hydration_revisions_hydration_id on hydration_revisions(hydration_id, id);
+
+drop table if exists user_settings;
+create table user_settings(
+    username varchar(255) not null
+    ,user_id varchar(255) not null default ''
+    ,daily_goal int not null
+    ,post_mode varchar(16) not null default ''
+    ,post_channel varchar(255) not null default ''
+    ,locale varchar(16) not null default ''
+    ,primary key (username)
+)
+;
+
+drop table if exists favorite_drinks;
+create table favorite_drinks(
+    id serial
+    ,username varchar(255) not null
+    ,drink varchar(255) not null
+    ,amount int not null
+    ,primary key (id)
+    ,unique (username, drink, amount)
+)
+;
+
+drop table if exists audit_logs;
+create table audit_logs(
+    id serial
+    ,actor varchar(255) not null
+    ,action varchar(32) not null
+    ,hydration_id int not null
+    ,username varchar(255) not null
+    ,before jsonb
+    ,after jsonb
+    ,source varchar(16) not null
+    ,created_at timestamp not null
+    ,primary key (id)
+)
+;
+create index audit_logs_username on audit_logs(username, id);
+create index audit_logs_actor on audit_logs(actor, id);
+create index audit_logs_hydration_id on audit_logs(hydration_id, id);
+
+drop table if exists slack_deliveries;
+create table slack_deliveries(
+    delivery_id varchar(512) not null
+    ,expires_at timestamp not null
+    ,primary key (delivery_id)
+)
+;
+create index slack_deliveries_expires_at on slack_deliveries(expires_at);
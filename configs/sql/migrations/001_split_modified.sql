@@ -0,0 +1,4 @@
+alter table hydrations rename column modified to drank_at;
+alter table hydrations add column updated_at timestamp;
+update hydrations set updated_at = drank_at;
+alter table hydrations alter column updated_at set not null;
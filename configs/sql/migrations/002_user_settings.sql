@@ -0,0 +1,6 @@
+create table user_settings(
+    username varchar(255) not null
+    ,daily_goal int not null
+    ,primary key (username)
+)
+;
@@ -0,0 +1,9 @@
+create table favorite_drinks(
+    id serial
+    ,username varchar(255) not null
+    ,drink varchar(255) not null
+    ,amount int not null
+    ,primary key (id)
+    ,unique (username, drink, amount)
+)
+;
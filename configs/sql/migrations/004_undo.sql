@@ -0,0 +1,19 @@
+alter table hydrations add column channel varchar(255) not null default '';
+alter table hydrations add column message_ts varchar(255) not null default '';
+alter table hydrations add column deleted_at timestamp;
+
+create table hydration_revisions(
+    id serial
+    ,hydration_id int not null
+    ,username varchar(255) not null
+    ,operation varchar(16) not null
+    ,drink varchar(255) not null
+    ,amount int not null
+    ,drank_at timestamp not null
+    ,created_at timestamp not null
+    ,undone_at timestamp
+    ,primary key (id)
+)
+;
+create index hydration_revisions_username on hydration_revisions(username, id);
+create index hydration_revisions_hydration_id on hydration_revisions(hydration_id, id);
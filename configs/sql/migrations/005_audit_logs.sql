@@ -0,0 +1,16 @@
+create table audit_logs(
+    id serial
+    ,actor varchar(255) not null
+    ,action varchar(32) not null
+    ,hydration_id int not null
+    ,username varchar(255) not null
+    ,before jsonb
+    ,after jsonb
+    ,source varchar(16) not null
+    ,created_at timestamp not null
+    ,primary key (id)
+)
+;
+create index audit_logs_username on audit_logs(username, id);
+create index audit_logs_actor on audit_logs(actor, id);
+create index audit_logs_hydration_id on audit_logs(hydration_id, id);
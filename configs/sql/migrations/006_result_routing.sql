@@ -0,0 +1,3 @@
+alter table user_settings add column user_id varchar(255) not null default '';
+alter table user_settings add column post_mode varchar(16) not null default '';
+alter table user_settings add column post_channel varchar(255) not null default '';
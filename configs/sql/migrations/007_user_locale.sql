@@ -0,0 +1 @@
+alter table user_settings add column locale varchar(16) not null default '';
@@ -0,0 +1,7 @@
+create table slack_deliveries(
+    delivery_id varchar(512) not null
+    ,expires_at timestamp not null
+    ,primary key (delivery_id)
+)
+;
+create index slack_deliveries_expires_at on slack_deliveries(expires_at);
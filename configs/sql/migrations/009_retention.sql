@@ -0,0 +1 @@
+create index hydrations_drank_at on hydrations(drank_at);
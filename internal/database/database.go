@@ -2,8 +2,10 @@ package database
 
 type (
 	// DbConfig describes database connection config.
+	// URL is used instead of Connection if it is set.
 	DbConfig struct {
 		Client     string `json:"client"`
+		URL        string `json:"url"`
 		Connection struct {
 			Host     string `json:"host"`
 			Port     int64  `json:"port"`
@@ -1,8 +0,0 @@
-package file
-
-import "os"
-
-func FileExists(filePath string) bool {
-	_, err := os.Stat(filePath)
-	return err == nil
-}
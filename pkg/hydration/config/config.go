@@ -1,17 +1,75 @@
 package config
 
 import (
+	"path/filepath"
+
 	"github.com/pirosuke/slack-bot-hydration/internal/database"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/logging"
 	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/slack"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/tracing"
 )
 
 type (
 	// Config describes global server config.
+	// Admins are Slack user IDs, as user names can be changed by users themselves.
 	Config struct {
 		Db                database.DbConfig `json:"db"`
 		ServerHost        string            `json:"host"`
 		LogDirPath        string            `json:"log_dir"`
+		LogLevel          string            `json:"log_level"`
+		LogOutput         string            `json:"log_output"`
 		PlotOutputDirPath string            `json:"plot_output_dir"`
+		PlotFontPath      string            `json:"plot_font_path"`
+		ViewsDirPath      string            `json:"views_dir"`
+		DailyGoal         int64             `json:"daily_goal"`
+		Admins            []string          `json:"admins"`
+		Routing           RoutingConfig     `json:"routing"`
 		Slack             slack.Config      `json:"slack"`
+		Tracing           tracing.Config    `json:"tracing"`
+		Retention         RetentionConfig   `json:"retention"`
+	}
+
+	// RetentionConfig describes how long hydration data is kept. Records drunk more than Days ago are deleted,
+	// or lose user and message if Mode is anonymize. Data is kept forever if Days is 0.
+	RetentionConfig struct {
+		Days int    `json:"days"`
+		Mode string `json:"mode"`
+	}
+
+	// Routing describes where result messages are posted.
+	Routing struct {
+		Mode    string `json:"mode"`
+		Channel string `json:"channel"`
+	}
+
+	// RoutingConfig describes default routing and routing of each workspace.
+	RoutingConfig struct {
+		Routing
+		Workspaces map[string]Routing `json:"workspaces"`
 	}
 )
+
+// Modes of retention.
+const (
+	RetentionDelete    = "delete"
+	RetentionAnonymize = "anonymize"
+)
+
+// LogOutputPath returns where logs are written. It is log_output if set, app.log in log_dir if set, or stdout.
+func (config Config) LogOutputPath() string {
+	if len(config.LogOutput) > 0 {
+		return config.LogOutput
+	}
+	if len(config.LogDirPath) > 0 {
+		return filepath.Join(config.LogDirPath, "app.log")
+	}
+	return logging.OutputStdout
+}
+
+// ForWorkspace returns routing of workspace falling back to default routing.
+func (routingConfig RoutingConfig) ForWorkspace(teamID string) Routing {
+	if routing, ok := routingConfig.Workspaces[teamID]; ok {
+		return routing
+	}
+	return routingConfig.Routing
+}
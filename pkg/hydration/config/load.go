@@ -0,0 +1,258 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/logging"
+)
+
+// Purpose selects fields which binary requires.
+type Purpose int
+
+const (
+	// ForServer requires fields used by interaction server.
+	ForServer Purpose = iota
+	// ForPlot requires fields used by weekly report.
+	ForPlot
+	// ForCLI requires fields used by admin CLI, which only needs database.
+	ForCLI
+)
+
+// FileName is name of config file in configs directory.
+const FileName = "config.json"
+
+// secretFileSuffix is appended to name of environment variable whose value is read from file.
+const secretFileSuffix = "_FILE"
+
+type (
+	// ValidationError lists every missing or invalid field of config.
+	ValidationError struct {
+		Problems []string
+	}
+
+	// envVar binds environment variable to config field.
+	envVar struct {
+		name string
+		set  func(config *Config, value string) error
+	}
+)
+
+// Error returns all problems one per line.
+func (err *ValidationError) Error() string {
+	return "Invalid config:\n  " + strings.Join(err.Problems, "\n  ")
+}
+
+// routingModes are modes accepted by routing package.
+var routingModes = map[string]bool{"channel": true, "dm": true, "origin": true}
+
+// envVars are environment variables which override config file.
+// Each variable can also be given as path to file containing value with _FILE suffix, e.g. HYDRATION_SLACK_TOKEN_FILE.
+var envVars = []envVar{
+	{"HYDRATION_HOST", func(config *Config, value string) error { config.ServerHost = value; return nil }},
+	{"HYDRATION_LOG_DIR", func(config *Config, value string) error { config.LogDirPath = value; return nil }},
+	{"HYDRATION_LOG_LEVEL", func(config *Config, value string) error { config.LogLevel = value; return nil }},
+	{"HYDRATION_LOG_OUTPUT", func(config *Config, value string) error { config.LogOutput = value; return nil }},
+	{"HYDRATION_PLOT_OUTPUT_DIR", func(config *Config, value string) error { config.PlotOutputDirPath = value; return nil }},
+	{"HYDRATION_PLOT_FONT_PATH", func(config *Config, value string) error { config.PlotFontPath = value; return nil }},
+	{"HYDRATION_VIEWS_DIR", func(config *Config, value string) error { config.ViewsDirPath = value; return nil }},
+	{"HYDRATION_DAILY_GOAL", func(config *Config, value string) error {
+		dailyGoal, err := strconv.ParseInt(value, 10, 64)
+		config.DailyGoal = dailyGoal
+		return err
+	}},
+	{"HYDRATION_ADMINS", func(config *Config, value string) error {
+		config.Admins = nil
+		for _, admin := range strings.Split(value, ",") {
+			if admin = strings.TrimSpace(admin); len(admin) > 0 {
+				config.Admins = append(config.Admins, admin)
+			}
+		}
+		return nil
+	}},
+	{"HYDRATION_ROUTING_MODE", func(config *Config, value string) error { config.Routing.Mode = value; return nil }},
+	{"HYDRATION_ROUTING_CHANNEL", func(config *Config, value string) error { config.Routing.Channel = value; return nil }},
+	{"HYDRATION_SLACK_TOKEN", func(config *Config, value string) error { config.Slack.Token = value; return nil }},
+	{"HYDRATION_SLACK_SIGNING_SECRET", func(config *Config, value string) error { config.Slack.SigningSecret = value; return nil }},
+	{"HYDRATION_SLACK_METADATA_SECRET", func(config *Config, value string) error { config.Slack.MetadataSecret = value; return nil }},
+	{"HYDRATION_TRACING_ENDPOINT", func(config *Config, value string) error { config.Tracing.Endpoint = value; return nil }},
+	{"HYDRATION_TRACING_INSECURE", func(config *Config, value string) error {
+		insecure, err := strconv.ParseBool(value)
+		config.Tracing.Insecure = insecure
+		return err
+	}},
+	{"HYDRATION_TRACING_SAMPLE_RATIO", func(config *Config, value string) error {
+		sampleRatio, err := strconv.ParseFloat(value, 64)
+		config.Tracing.SampleRatio = sampleRatio
+		return err
+	}},
+	{"HYDRATION_RETENTION_DAYS", func(config *Config, value string) error {
+		days, err := strconv.Atoi(value)
+		config.Retention.Days = days
+		return err
+	}},
+	{"HYDRATION_RETENTION_MODE", func(config *Config, value string) error { config.Retention.Mode = value; return nil }},
+	{"DATABASE_URL", func(config *Config, value string) error { config.Db.URL = value; return nil }},
+	{"HYDRATION_DB_HOST", func(config *Config, value string) error { config.Db.Connection.Host = value; return nil }},
+	{"HYDRATION_DB_PORT", func(config *Config, value string) error {
+		port, err := strconv.ParseInt(value, 10, 64)
+		config.Db.Connection.Port = port
+		return err
+	}},
+	{"HYDRATION_DB_NAME", func(config *Config, value string) error { config.Db.Connection.Database = value; return nil }},
+	{"HYDRATION_DB_USER", func(config *Config, value string) error { config.Db.Connection.User = value; return nil }},
+	{"HYDRATION_DB_PASSWORD", func(config *Config, value string) error { config.Db.Connection.Password = value; return nil }},
+}
+
+// Default returns config used when no source sets field.
+func Default() Config {
+	config := Config{
+		ServerHost: ":18081",
+		DailyGoal:  2000,
+	}
+	config.Db.Client = "postgresql"
+	config.Db.Connection.Port = 5432
+	config.Routing.Mode = "channel"
+	config.Tracing.SampleRatio = 1
+	config.Retention.Mode = RetentionDelete
+
+	return config
+}
+
+// Load reads config from defaults, config.json in configs directory and environment variables in this order,
+// and validates fields required for purpose.
+// Config file is not read if configs directory path is empty.
+func Load(configsDirPath string, purpose Purpose) (Config, error) {
+	config := Default()
+
+	if len(configsDirPath) > 0 {
+		configFilePath := filepath.Join(configsDirPath, FileName)
+		jsonContent, err := ioutil.ReadFile(configFilePath)
+		if err != nil {
+			return config, fmt.Errorf("Failed reading config file: %v", err)
+		}
+
+		if err := json.Unmarshal(jsonContent, &config); err != nil {
+			return config, fmt.Errorf("Invalid config file %s: %v", configFilePath, err)
+		}
+	}
+
+	problems := applyEnv(&config, os.LookupEnv)
+	problems = append(problems, config.problems(purpose)...)
+	if len(problems) > 0 {
+		return config, &ValidationError{Problems: problems}
+	}
+
+	return config, nil
+}
+
+// applyEnv sets fields from environment variables and returns problems of their values.
+func applyEnv(config *Config, lookupEnv func(name string) (string, bool)) []string {
+	var problems []string
+
+	for _, v := range envVars {
+		value, ok := lookupEnv(v.name)
+
+		if filePath, fileOK := lookupEnv(v.name + secretFileSuffix); fileOK {
+			if ok {
+				problems = append(problems, fmt.Sprintf("%s and %s%s must not be set together", v.name, v.name, secretFileSuffix))
+				continue
+			}
+
+			content, err := ioutil.ReadFile(filePath)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s%s can not be read: %v", v.name, secretFileSuffix, err))
+				continue
+			}
+			value, ok = strings.TrimRight(string(content), "\r\n"), true
+		}
+
+		if !ok {
+			continue
+		}
+
+		if err := v.set(config, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s is invalid: %q", v.name, value))
+		}
+	}
+
+	return problems
+}
+
+// slackUserID matches IDs of Slack users, which start with U or W for Enterprise Grid.
+var slackUserID = regexp.MustCompile(`^[UW][A-Z0-9]+$`)
+
+// problems returns missing or invalid fields required for purpose.
+func (config Config) problems(purpose Purpose) []string {
+	var problems []string
+	required := func(name string, value string) {
+		if len(value) == 0 {
+			problems = append(problems, name+" is required")
+		}
+	}
+
+	if config.Db.Client != "postgresql" {
+		problems = append(problems, fmt.Sprintf("db.client is invalid: %q", config.Db.Client))
+	}
+	if len(config.Db.URL) == 0 {
+		required("db.connection.host", config.Db.Connection.Host)
+		required("db.connection.database", config.Db.Connection.Database)
+		required("db.connection.user", config.Db.Connection.User)
+		if config.Db.Connection.Port <= 0 || config.Db.Connection.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("db.connection.port is invalid: %d", config.Db.Connection.Port))
+		}
+	}
+
+	if _, err := logging.ParseLevel(config.LogLevel); err != nil {
+		problems = append(problems, fmt.Sprintf("log_level is invalid: %q", config.LogLevel))
+	}
+
+	if config.Tracing.SampleRatio < 0 || config.Tracing.SampleRatio > 1 {
+		problems = append(problems, fmt.Sprintf("tracing.sample_ratio is invalid: %v", config.Tracing.SampleRatio))
+	}
+
+	if config.Retention.Days < 0 {
+		problems = append(problems, fmt.Sprintf("retention.days is invalid: %d", config.Retention.Days))
+	}
+	if config.Retention.Mode != RetentionDelete && config.Retention.Mode != RetentionAnonymize {
+		problems = append(problems, fmt.Sprintf("retention.mode is invalid: %q", config.Retention.Mode))
+	}
+
+	for _, admin := range config.Admins {
+		if !slackUserID.MatchString(admin) {
+			problems = append(problems, fmt.Sprintf("admins must be Slack user IDs: %q", admin))
+		}
+	}
+
+	switch purpose {
+	case ForServer:
+		required("slack.token", config.Slack.Token)
+		required("host", config.ServerHost)
+		required("slack.metadata_secret", config.Slack.MetadataSecret)
+		required("slack.signing_secret", config.Slack.SigningSecret)
+
+		if config.DailyGoal < 0 {
+			problems = append(problems, fmt.Sprintf("daily_goal is invalid: %d", config.DailyGoal))
+		}
+
+		if len(config.Routing.Mode) > 0 && !routingModes[config.Routing.Mode] {
+			problems = append(problems, fmt.Sprintf("routing.mode is invalid: %q", config.Routing.Mode))
+		}
+		for teamID, routing := range config.Routing.Workspaces {
+			if len(routing.Mode) > 0 && !routingModes[routing.Mode] {
+				problems = append(problems, fmt.Sprintf("routing.workspaces.%s.mode is invalid: %q", teamID, routing.Mode))
+			}
+		}
+	case ForPlot:
+		required("slack.token", config.Slack.Token)
+		required("plot_output_dir", config.PlotOutputDirPath)
+	}
+
+	return problems
+}
@@ -0,0 +1,94 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+)
+
+// Identifiers of water samples in Apple Health export.
+const (
+	appleWaterType   = "HKQuantityTypeIdentifierDietaryWater"
+	appleFoodTypeKey = "HKFoodType"
+	appleTimeLayout  = "2006-01-02 15:04:05 -0700"
+	appleSourceName  = "slack-bot-hydration"
+)
+
+type (
+	// appleRecord describes Record element of Apple Health export.
+	appleRecord struct {
+		XMLName      xml.Name        `xml:"Record"`
+		Type         string          `xml:"type,attr"`
+		SourceName   string          `xml:"sourceName,attr"`
+		Unit         string          `xml:"unit,attr"`
+		CreationDate string          `xml:"creationDate,attr"`
+		StartDate    string          `xml:"startDate,attr"`
+		EndDate      string          `xml:"endDate,attr"`
+		Value        string          `xml:"value,attr"`
+		Metadata     []appleMetadata `xml:"MetadataEntry"`
+	}
+
+	// appleMetadata describes MetadataEntry element of Apple Health export.
+	appleMetadata struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:"value,attr"`
+	}
+
+	appleHealthWriter struct {
+		w       *bufio.Writer
+		encoder *xml.Encoder
+		loc     *time.Location
+	}
+)
+
+// appleHealthData is root element of Apple Health export.
+var appleHealthData = xml.StartElement{
+	Name: xml.Name{Local: "HealthData"},
+	Attr: []xml.Attr{{Name: xml.Name{Local: "locale"}, Value: "en_US"}},
+}
+
+// newAppleHealthWriter returns writer of water samples in format of export.xml of Apple Health.
+func newAppleHealthWriter(w io.Writer, loc *time.Location) (Writer, error) {
+	writer := &appleHealthWriter{w: bufio.NewWriter(w), loc: loc}
+	writer.encoder = xml.NewEncoder(writer.w)
+	writer.encoder.Indent("", " ")
+
+	if _, err := writer.w.WriteString(xml.Header); err != nil {
+		return nil, err
+	}
+	return writer, writer.encoder.EncodeToken(appleHealthData)
+}
+
+// Write writes hydration as water sample. Drink is kept as food type.
+func (writer *appleHealthWriter) Write(hydration models.Hydration) error {
+	drankAt := InLocation(hydration.DrankAt, writer.loc).Format(appleTimeLayout)
+
+	return writer.encoder.Encode(appleRecord{
+		Type:         appleWaterType,
+		SourceName:   appleSourceName,
+		Unit:         "mL",
+		CreationDate: InLocation(hydration.UpdatedAt, writer.loc).Format(appleTimeLayout),
+		StartDate:    drankAt,
+		EndDate:      drankAt,
+		Value:        strconv.FormatInt(hydration.Amount, 10),
+		Metadata:     []appleMetadata{{Key: appleFoodTypeKey, Value: hydration.Drink}},
+	})
+}
+
+// Close closes root element and flushes buffer.
+func (writer *appleHealthWriter) Close() error {
+	if err := writer.encoder.EncodeToken(appleHealthData.End()); err != nil {
+		return err
+	}
+	if err := writer.encoder.Flush(); err != nil {
+		return err
+	}
+	if _, err := writer.w.WriteString("\n"); err != nil {
+		return err
+	}
+	return writer.w.Flush()
+}
@@ -0,0 +1,167 @@
+// Package export writes hydration history of user as CSV, JSON, Apple Health or Google Fit data one record at a time.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+)
+
+// Formats of exported files.
+const (
+	FormatCSV         = "csv"
+	FormatJSON        = "json"
+	FormatAppleHealth = "apple_health"
+	FormatGoogleFit   = "google_fit"
+)
+
+// header is columns of CSV and keys of JSON objects.
+var header = []string{"id", "drink", "amount", "drank_at", "updated_at"}
+
+type (
+	// Writer writes hydration data one by one.
+	Writer interface {
+		// Write writes one hydration.
+		Write(hydration models.Hydration) error
+		// Close writes end of file and flushes buffer. Underlying writer is not closed.
+		Close() error
+	}
+
+	// record describes hydration in exported file. Times are in RFC 3339 with offset of user's time zone.
+	record struct {
+		ID        int64  `json:"id"`
+		Drink     string `json:"drink"`
+		Amount    int64  `json:"amount"`
+		DrankAt   string `json:"drank_at"`
+		UpdatedAt string `json:"updated_at"`
+	}
+
+	csvWriter struct {
+		w   *csv.Writer
+		loc *time.Location
+	}
+
+	jsonWriter struct {
+		w     *bufio.Writer
+		loc   *time.Location
+		count int
+	}
+)
+
+// IsFormat returns true if format is supported.
+func IsFormat(format string) bool {
+	return len(Extension(format)) > 0
+}
+
+// Extension returns file extension of format, or empty string if format is not supported.
+func Extension(format string) string {
+	switch format {
+	case FormatCSV:
+		return "csv"
+	case FormatJSON, FormatGoogleFit:
+		return "json"
+	case FormatAppleHealth:
+		return "xml"
+	}
+	return ""
+}
+
+// NewWriter returns writer which writes hydration data to w in format, with times in loc.
+func NewWriter(format string, w io.Writer, loc *time.Location) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		writer := &csvWriter{w: csv.NewWriter(w), loc: loc}
+		return writer, writer.w.Write(header)
+	case FormatJSON:
+		return &jsonWriter{w: bufio.NewWriter(w), loc: loc}, nil
+	case FormatAppleHealth:
+		return newAppleHealthWriter(w, loc)
+	case FormatGoogleFit:
+		return &googleFitWriter{w: bufio.NewWriter(w)}, nil
+	}
+	return nil, fmt.Errorf("Unknown export format: %s", format)
+}
+
+// FileName returns name of file exported for user.
+func FileName(userName string, format string, now time.Time) string {
+	name := "hydration"
+	if format == FormatAppleHealth || format == FormatGoogleFit {
+		name += "-" + strings.ReplaceAll(format, "_", "-")
+	}
+	return fmt.Sprintf("%s-%s-%s.%s", name, userName, now.Format("20060102"), Extension(format))
+}
+
+// newRecord returns hydration with times in loc.
+func newRecord(hydration models.Hydration, loc *time.Location) record {
+	return record{
+		ID:        hydration.ID,
+		Drink:     hydration.Drink,
+		Amount:    hydration.Amount,
+		DrankAt:   InLocation(hydration.DrankAt, loc).Format(time.RFC3339),
+		UpdatedAt: InLocation(hydration.UpdatedAt, loc).Format(time.RFC3339),
+	}
+}
+
+// InLocation returns time saved without time zone in loc.
+// Times are saved in local time of server, so wall clock of t is read in time.Local.
+func InLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local).In(loc)
+}
+
+// Write writes hydration as CSV row.
+func (writer *csvWriter) Write(hydration models.Hydration) error {
+	r := newRecord(hydration, writer.loc)
+	return writer.w.Write([]string{
+		strconv.FormatInt(r.ID, 10),
+		r.Drink,
+		strconv.FormatInt(r.Amount, 10),
+		r.DrankAt,
+		r.UpdatedAt,
+	})
+}
+
+// Close flushes rows which are not written yet.
+func (writer *csvWriter) Close() error {
+	writer.w.Flush()
+	return writer.w.Error()
+}
+
+// Write writes hydration as element of JSON array.
+func (writer *jsonWriter) Write(hydration models.Hydration) error {
+	recordJSON, err := json.Marshal(newRecord(hydration, writer.loc))
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n  "
+	if writer.count == 0 {
+		separator = "[\n  "
+	}
+	writer.count++
+
+	if _, err := writer.w.WriteString(separator); err != nil {
+		return err
+	}
+	_, err = writer.w.Write(recordJSON)
+	return err
+}
+
+// Close closes JSON array and flushes buffer.
+func (writer *jsonWriter) Close() error {
+	end := "\n]\n"
+	if writer.count == 0 {
+		end = "[]\n"
+	}
+
+	if _, err := writer.w.WriteString(end); err != nil {
+		return err
+	}
+	return writer.w.Flush()
+}
@@ -0,0 +1,117 @@
+package export_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/export"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/i18n"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/importer"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+)
+
+// samplesDirPath is directory of sample files checked into repository.
+var samplesDirPath = filepath.Join("..", "..", "..", "configs", "samples")
+
+// now is time of import, which is after all samples. Records are imported in time.Local as server saves them.
+var now = time.Date(2020, 6, 1, 0, 0, 0, 0, time.Local)
+
+// importSample returns records of sample file.
+func importSample(t *testing.T, fileName string) []models.Hydration {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join(samplesDirPath, fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	rows, err := importer.Read(file, importer.FormatOf(fileName), importer.DefaultMapping, time.UTC, i18n.English, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hydrations(t, rows)
+}
+
+// hydrations returns records of rows, failing test if any row is invalid.
+func hydrations(t *testing.T, rows []importer.Row) []models.Hydration {
+	t.Helper()
+
+	var hydrationList []models.Hydration
+	for _, row := range rows {
+		if row.Status != importer.StatusNew {
+			t.Fatalf("row %d: status = %s (%s)", row.Number, row.Status, row.Problem)
+		}
+		hydrationList = append(hydrationList, row.Hydration)
+	}
+	return hydrationList
+}
+
+func TestRoundTripSamples(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone data is not available:", err)
+	}
+
+	formats := []struct {
+		format    string
+		keepDrink bool
+	}{
+		{export.FormatCSV, true},
+		{export.FormatJSON, true},
+		{export.FormatAppleHealth, true},
+		{export.FormatGoogleFit, false},
+	}
+
+	for _, sampleName := range []string{"apple_health_export.xml", "google_fit_hydration.json"} {
+		samples := importSample(t, sampleName)
+		if len(samples) == 0 {
+			t.Fatalf("%s: no records", sampleName)
+		}
+
+		for _, f := range formats {
+			t.Run(sampleName+"/"+f.format, func(t *testing.T) {
+				var buf bytes.Buffer
+				writer, err := export.NewWriter(f.format, &buf, loc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, hydration := range samples {
+					if err := writer.Write(hydration); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := writer.Close(); err != nil {
+					t.Fatal(err)
+				}
+
+				fileName := export.FileName("alice", f.format, now)
+				rows, err := importer.Read(&buf, importer.FormatOf(fileName), importer.DefaultMapping, time.UTC, i18n.English, now)
+				if err != nil {
+					t.Fatalf("reading %s: %v\n%s", fileName, err, buf.String())
+				}
+				imported := hydrations(t, rows)
+
+				if len(imported) != len(samples) {
+					t.Fatalf("imported %d records, want %d", len(imported), len(samples))
+				}
+				for i, want := range samples {
+					got := imported[i]
+
+					wantDrink := want.Drink
+					if !f.keepDrink {
+						wantDrink = i18n.T(i18n.English, "import.water")
+					}
+					if got.Drink != wantDrink || got.Amount != want.Amount || !got.DrankAt.Equal(want.DrankAt) {
+						t.Errorf("record %d: got %s %dml at %s, want %s %dml at %s", i,
+							got.Drink, got.Amount, got.DrankAt.Format(time.RFC3339),
+							wantDrink, want.Amount, want.DrankAt.Format(time.RFC3339))
+					}
+				}
+			})
+		}
+	}
+}
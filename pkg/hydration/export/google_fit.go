@@ -0,0 +1,88 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+)
+
+// Identifiers of hydration data in Google Fit. googleFitDataSourceID is placeholder which is replaced
+// with ID of data source created for user before dataset is sent to Fitness API.
+const (
+	googleFitDataType     = "com.google.hydration"
+	googleFitDataSourceID = "raw:com.google.hydration:slack-bot-hydration"
+)
+
+type (
+	// googleFitPoint describes data point of dataset of Fitness API. Times are nanoseconds since epoch.
+	googleFitPoint struct {
+		DataTypeName   string           `json:"dataTypeName"`
+		StartTimeNanos string           `json:"startTimeNanos"`
+		EndTimeNanos   string           `json:"endTimeNanos"`
+		Value          []googleFitValue `json:"value"`
+	}
+
+	// googleFitValue describes value of data point. Hydration is in liters.
+	googleFitValue struct {
+		FpVal float64 `json:"fpVal"`
+	}
+
+	googleFitWriter struct {
+		w        *bufio.Writer
+		count    int
+		minStart int64
+		maxEnd   int64
+	}
+)
+
+// Write writes hydration as data point of dataset. Drink is not kept as Google Fit has no field for it.
+func (writer *googleFitWriter) Write(hydration models.Hydration) error {
+	drankAt := InLocation(hydration.DrankAt, time.Local).UnixNano()
+	pointJSON, err := json.Marshal(googleFitPoint{
+		DataTypeName:   googleFitDataType,
+		StartTimeNanos: strconv.FormatInt(drankAt, 10),
+		EndTimeNanos:   strconv.FormatInt(drankAt, 10),
+		Value:          []googleFitValue{{FpVal: float64(hydration.Amount) / 1000}},
+	})
+	if err != nil {
+		return err
+	}
+
+	separator := ",\n    "
+	if writer.count == 0 {
+		separator = "{\n  \"dataSourceId\": \"" + googleFitDataSourceID + "\",\n  \"point\": [\n    "
+		writer.minStart = drankAt
+	}
+	writer.count++
+
+	if drankAt < writer.minStart {
+		writer.minStart = drankAt
+	}
+	if drankAt > writer.maxEnd {
+		writer.maxEnd = drankAt
+	}
+
+	if _, err := writer.w.WriteString(separator); err != nil {
+		return err
+	}
+	_, err = writer.w.Write(pointJSON)
+	return err
+}
+
+// Close writes time range of data points, which Fitness API requires, and flushes buffer.
+func (writer *googleFitWriter) Close() error {
+	end := "\n  ],\n"
+	if writer.count == 0 {
+		end = "{\n  \"dataSourceId\": \"" + googleFitDataSourceID + "\",\n  \"point\": [],\n"
+	}
+	end += "  \"minStartTimeNs\": \"" + strconv.FormatInt(writer.minStart, 10) + "\",\n" +
+		"  \"maxEndTimeNs\": \"" + strconv.FormatInt(writer.maxEnd, 10) + "\"\n}\n"
+
+	if _, err := writer.w.WriteString(end); err != nil {
+		return err
+	}
+	return writer.w.Flush()
+}
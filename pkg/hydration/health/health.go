@@ -0,0 +1,140 @@
+// Package health runs checks of dependencies for readiness probes.
+package health
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Statuses of checks and reports.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+type (
+	// Check returns error if dependency is not usable.
+	Check func() error
+
+	// Checker runs registered checks concurrently.
+	Checker struct {
+		timeout time.Duration
+		checks  []*namedCheck
+	}
+
+	// Report describes result of all checks.
+	// Checks holds "ok" or error message keyed by check name.
+	Report struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}
+
+	namedCheck struct {
+		name  string
+		check Check
+		ttl   time.Duration
+
+		mu        sync.Mutex
+		checkedAt time.Time
+		err       error
+	}
+)
+
+// New returns checker which gives up checks taking longer than timeout.
+func New(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers check run on every Run.
+func (checker *Checker) Add(name string, check Check) {
+	checker.AddCached(name, 0, check)
+}
+
+// AddCached registers check whose result is reused for ttl.
+// It is used for checks calling rate limited APIs.
+func (checker *Checker) AddCached(name string, ttl time.Duration, check Check) {
+	checker.checks = append(checker.checks, &namedCheck{name: name, check: check, ttl: ttl})
+}
+
+// Run runs all checks and returns their results.
+func (checker *Checker) Run() Report {
+	type result struct {
+		name string
+		err  error
+	}
+
+	results := make(chan result, len(checker.checks))
+	for _, check := range checker.checks {
+		go func(check *namedCheck) {
+			results <- result{check.name, check.run()}
+		}(check)
+	}
+
+	report := Report{Status: StatusOK, Checks: map[string]string{}}
+	for _, check := range checker.checks {
+		report.Checks[check.name] = "timeout"
+	}
+
+	timeout := time.After(checker.timeout)
+	for range checker.checks {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				report.Checks[r.name] = r.err.Error()
+			} else {
+				report.Checks[r.name] = StatusOK
+			}
+		case <-timeout:
+			report.Status = StatusUnavailable
+			return report
+		}
+	}
+
+	for _, status := range report.Checks {
+		if status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+
+	return report
+}
+
+// OK returns whether all checks passed.
+func (report Report) OK() bool {
+	return report.Status == StatusOK
+}
+
+// Err returns error listing failed checks, or nil if all checks passed.
+func (report Report) Err() error {
+	if report.OK() {
+		return nil
+	}
+
+	var failures []string
+	for name, status := range report.Checks {
+		if status != StatusOK {
+			failures = append(failures, name+": "+status)
+		}
+	}
+	sort.Strings(failures)
+
+	return fmt.Errorf("Dependencies are unavailable: %s", strings.Join(failures, ", "))
+}
+
+// run runs check or returns its cached result.
+func (check *namedCheck) run() error {
+	check.mu.Lock()
+	defer check.mu.Unlock()
+
+	if check.ttl > 0 && !check.checkedAt.IsZero() && time.Since(check.checkedAt) < check.ttl {
+		return check.err
+	}
+
+	check.err = check.check()
+	check.checkedAt = time.Now()
+
+	return check.err
+}
@@ -0,0 +1,138 @@
+package i18n
+
+var en = map[string]string{
+	"common.close":  "Close",
+	"common.cancel": "Cancel",
+
+	"record_form.title":               "Log a drink",
+	"record_form.submit":              "Save",
+	"record_form.drink":               "Drink",
+	"record_form.drink_placeholder":   "What did you drink?",
+	"record_form.amount":              "Amount",
+	"record_form.drank_date":          "Date",
+	"record_form.drank_time":          "Time",
+	"record_form.channel":             "Post to channel",
+	"record_form.channel_placeholder": "Use my settings",
+
+	"result.text":     "@%s had a drink\nToday's total is %sml",
+	"result.drink":    "*Drink:*",
+	"result.amount":   "*Amount:*",
+	"result.drank_at": "*Time:*",
+
+	"button.repeat":   "Repeat",
+	"button.favorite": "Favorite",
+	"button.edit":     "Edit",
+	"button.delete":   "Delete",
+	"button.undo":     "Undo",
+	"button.unpin":    "Unpin",
+
+	"delete_confirm.title":   "Delete record",
+	"delete_confirm.text":    "Do you want to delete this record?",
+	"delete_confirm.confirm": "Delete",
+
+	"home.header":      "Hydration dashboard",
+	"home.today":       "*Today:* %sml / goal %sml",
+	"home.week":        "*Last 7 days:*",
+	"home.range":       "%s – %s (max %sml)",
+	"home.record":      "Log a drink",
+	"home.settings":    "Settings",
+	"home.export_csv":  "Export CSV",
+	"home.export_json": "Export JSON",
+	"home.delete_data": "Delete all my data",
+	"home.recent":      "*Recent records*",
+	"home.empty":       "No records yet",
+
+	"favorites.title": "Favorites",
+	"favorites.hint":  "Tap to log right away",
+	"favorites.empty": "You have no favorite drinks yet",
+
+	"settings.title":             "Settings",
+	"settings.submit":            "Save",
+	"settings.daily_goal":        "Daily goal (ml)",
+	"settings.post_mode":         "Post records to",
+	"settings.post_mode.default": "Workspace default",
+	"settings.post_mode.channel": "A channel",
+	"settings.post_mode.dm":      "My DM",
+	"settings.post_mode.origin":  "Where I used the bot",
+	"settings.post_channel":      "Channel",
+	"settings.post_channel_hint": "Used when \"A channel\" is selected",
+	"settings.locale":            "Language",
+	"settings.locale.default":    "Use Slack setting",
+	"settings.locale.ja":         "日本語",
+	"settings.locale.en":         "English",
+
+	"undo.add":    "Logged \"%s\"",
+	"undo.update": "Updated the record of \"%s\"",
+	"undo.delete": "Deleted the record of \"%s\"",
+
+	"alert.undo_failed.title":      "Can't undo",
+	"alert.undo_failed.text":       "This operation can no longer be undone",
+	"alert.invalid_form.title":     "Can't save",
+	"alert.invalid_form.text":      "The form is invalid. Please try again",
+	"alert.update_forbidden.title": "Can't update",
+	"alert.update_forbidden.text":  "You are not allowed to update this record",
+	"alert.delete_forbidden.title": "Can't delete",
+	"alert.delete_forbidden.text":  "You are not allowed to delete this record",
+	"alert.pin_forbidden.title":    "Can't pin",
+	"alert.pin_forbidden.text":     "You are not allowed to pin this record",
+	"alert.pin_full.title":         "Can't pin",
+	"alert.pin_full.text":          "You can pin up to %d drinks. Unpin one in App Home first",
+
+	"command.usage":          "Usage:\n`%s undo` undo your last operation\n`%s export [csv|json|apple_health|google_fit]` get all your records as a file in DM\n`%s delete-my-data` delete all your records, favorites and settings",
+	"command.audit_usage":    "Usage:\n`%s audit user <user name>`\n`%s audit record <record ID>`",
+	"command.undo_none":      "There is nothing to undo",
+	"command.undo_failed":    "Failed to undo",
+	"command.undo_done":      "Undid your last operation",
+	"command.audit_none":     "No matching records",
+	"command.export_usage":   "Usage:\n`%s export [csv|json|apple_health|google_fit]`",
+	"command.export_started": "Exporting your records. The file will be sent to your DM.",
+	"command.purge_usage":    "Usage:\n`%s purge <user name>` delete all data of user",
+
+	"export.caption": "Here are all your hydration records",
+	"undo.done":      "Undone",
+
+	"import.amount_invalid":   "Amount %q cannot be read",
+	"import.unit_invalid":     "Unit %q is not supported",
+	"import.water":            "Water",
+	"import.time_invalid":     "Date and time %q cannot be read",
+	"import.report":           "%s: %d records will be added. %d already recorded and %d invalid records are skipped.",
+	"import.invalid_row":      "Row %d: %s",
+	"import.more_invalid":     "…and %d more invalid records",
+	"import.nothing":          "There are no new records to add.",
+	"import.confirm":          "Import",
+	"import.cancel":           "Cancel",
+	"import.done":             "Added %d records from %s.",
+	"import.cancelled":        "Import of %s was cancelled.",
+	"import.failed":           "Could not import %s: %s",
+	"import.error":            "something went wrong. Try again later",
+	"import.mapping_too_long": "mapping of columns is too long",
+	"import.unknown_format":   "%s cannot be imported. Share a CSV or JSON file.",
+	"import.too_large":        "%s is too large. Files up to %d MB can be imported.",
+
+	"data.delete_button":        "Delete",
+	"data.delete_confirm_title": "Delete data",
+	"data.delete_confirm_text":  "All your records, favorites, settings and their history will be deleted. This cannot be undone.",
+	"data.delete_prompt":        "Press the button to delete all your records, favorites and settings.",
+	"data.deleted":              "Deleted all your data (%d records).",
+	"data.purge_prompt":         "Press the button to delete all data of %s.",
+	"data.purge_confirm_text":   "All records, favorites, settings and their history of %s will be deleted. This cannot be undone.",
+	"data.purged":               "Deleted all data of %s (%d records).",
+	"data.delete_failed":        "Could not delete data. Try again later.",
+
+	"validation.drink_required":      "Enter a drink",
+	"validation.drink_too_long":      "Drink must be %d characters or less",
+	"validation.amount_required":     "Select an amount",
+	"validation.amount_range":        "Amount must be between %dml and %dml",
+	"validation.date_required":       "Select a date",
+	"validation.time_required":       "Select a time",
+	"validation.time_future":         "Time can't be in the future",
+	"validation.daily_goal_range":    "Daily goal must be between %dml and %dml",
+	"validation.post_mode_required":  "Select where to post",
+	"validation.post_channel_needed": "Select a channel",
+	"validation.locale_required":     "Select a language",
+
+	"report.title":   "Weekly hydration",
+	"report.x_label": "Day",
+	"report.y_label": "Amount",
+	"report.caption": "Here is your hydration for the past week",
+}
@@ -0,0 +1,138 @@
+package i18n
+
+var ja = map[string]string{
+	"common.close":  "閉じる",
+	"common.cancel": "キャンセル",
+
+	"record_form.title":               "水分摂取量記録",
+	"record_form.submit":              "記録する",
+	"record_form.drink":               "飲み物の種類",
+	"record_form.drink_placeholder":   "何飲んだ？",
+	"record_form.amount":              "摂取量",
+	"record_form.drank_date":          "飲んだ日",
+	"record_form.drank_time":          "飲んだ時刻",
+	"record_form.channel":             "投稿先チャンネル",
+	"record_form.channel_placeholder": "設定に従う",
+
+	"result.text":     "@%s が飲み物を飲みました\n本日の合計量は %sml です",
+	"result.drink":    "*飲んだもの:*",
+	"result.amount":   "*摂取量:*",
+	"result.drank_at": "*飲んだ時刻:*",
+
+	"button.repeat":   "リピート",
+	"button.favorite": "お気に入り",
+	"button.edit":     "修正",
+	"button.delete":   "削除",
+	"button.undo":     "元に戻す",
+	"button.unpin":    "解除",
+
+	"delete_confirm.title":   "削除確認",
+	"delete_confirm.text":    "記録を削除しますか？",
+	"delete_confirm.confirm": "削除する",
+
+	"home.header":      "水分摂取量ダッシュボード",
+	"home.today":       "*本日の摂取量:* %sml / 目標 %sml",
+	"home.week":        "*過去7日間:*",
+	"home.range":       "%s 〜 %s (最大 %sml)",
+	"home.record":      "記録する",
+	"home.settings":    "設定",
+	"home.export_csv":  "CSVで書き出す",
+	"home.export_json": "JSONで書き出す",
+	"home.delete_data": "すべてのデータを削除",
+	"home.recent":      "*最近の記録*",
+	"home.empty":       "まだ記録がありません",
+
+	"favorites.title": "お気に入り",
+	"favorites.hint":  "タップするとすぐに記録します",
+	"favorites.empty": "お気に入りの飲み物がまだありません",
+
+	"settings.title":             "設定",
+	"settings.submit":            "保存する",
+	"settings.daily_goal":        "1日の目標摂取量 (ml)",
+	"settings.post_mode":         "記録の投稿先",
+	"settings.post_mode.default": "ワークスペースの設定に従う",
+	"settings.post_mode.channel": "指定したチャンネル",
+	"settings.post_mode.dm":      "自分へのDM",
+	"settings.post_mode.origin":  "操作したチャンネル",
+	"settings.post_channel":      "投稿先チャンネル",
+	"settings.post_channel_hint": "「指定したチャンネル」を選んだときに投稿されます",
+	"settings.locale":            "言語",
+	"settings.locale.default":    "Slackの設定に従う",
+	"settings.locale.ja":         "日本語",
+	"settings.locale.en":         "English",
+
+	"undo.add":    "「%s」を記録しました",
+	"undo.update": "「%s」の記録を更新しました",
+	"undo.delete": "「%s」の記録を削除しました",
+
+	"alert.undo_failed.title":      "元に戻せません",
+	"alert.undo_failed.text":       "この操作は元に戻せません",
+	"alert.invalid_form.title":     "記録できません",
+	"alert.invalid_form.text":      "フォームの情報が正しくありません。もう一度やり直してください",
+	"alert.update_forbidden.title": "更新できません",
+	"alert.update_forbidden.text":  "この記録を更新する権限がありません",
+	"alert.delete_forbidden.title": "削除できません",
+	"alert.delete_forbidden.text":  "この記録を削除する権限がありません",
+	"alert.pin_forbidden.title":    "お気に入りにできません",
+	"alert.pin_forbidden.text":     "この記録をお気に入りにする権限がありません",
+	"alert.pin_full.title":         "お気に入りにできません",
+	"alert.pin_full.text":          "お気に入りは%d件までです。App Homeで外してから追加してください",
+
+	"command.usage":          "使い方:\n`%s undo` 直前の操作を元に戻す\n`%s export [csv|json|apple_health|google_fit]` すべての記録をファイルでDMに送る\n`%s delete-my-data` すべての記録、お気に入り、設定を削除する",
+	"command.audit_usage":    "使い方:\n`%s audit user <ユーザー名>`\n`%s audit record <記録ID>`",
+	"command.undo_none":      "元に戻せる操作がありません",
+	"command.undo_failed":    "元に戻せませんでした",
+	"command.undo_done":      "直前の操作を元に戻しました",
+	"command.audit_none":     "該当する記録はありません",
+	"command.export_usage":   "使い方:\n`%s export [csv|json|apple_health|google_fit]`",
+	"command.export_started": "記録を書き出しています。ファイルはDMに送られます。",
+	"command.purge_usage":    "使い方:\n`%s purge <ユーザー名>` ユーザーのすべてのデータを削除する",
+
+	"export.caption": "すべての水分摂取の記録です",
+	"undo.done":      "元に戻しました",
+
+	"import.amount_invalid":   "摂取量 %q を読み取れません",
+	"import.unit_invalid":     "単位 %q には対応していません",
+	"import.water":            "水",
+	"import.time_invalid":     "日時 %q を読み取れません",
+	"import.report":           "%s: %d件の記録を追加します。記録済みの%d件と不正な%d件はスキップします。",
+	"import.invalid_row":      "%d行目: %s",
+	"import.more_invalid":     "…ほか%d件の不正な記録",
+	"import.nothing":          "追加する新しい記録はありません。",
+	"import.confirm":          "取り込む",
+	"import.cancel":           "キャンセル",
+	"import.done":             "%[2]sから%[1]d件の記録を追加しました。",
+	"import.cancelled":        "%sの取り込みをキャンセルしました。",
+	"import.failed":           "%sを取り込めませんでした: %s",
+	"import.error":            "エラーが発生しました。時間をおいて再度お試しください",
+	"import.mapping_too_long": "列の指定が長すぎます",
+	"import.unknown_format":   "%sは取り込めません。CSVかJSONのファイルを共有してください。",
+	"import.too_large":        "%sは大きすぎます。取り込めるのは%dMBまでのファイルです。",
+
+	"data.delete_button":        "削除する",
+	"data.delete_confirm_title": "データ削除",
+	"data.delete_confirm_text":  "すべての記録、お気に入り、設定とその履歴が削除されます。元に戻すことはできません。",
+	"data.delete_prompt":        "ボタンを押すと、すべての記録、お気に入り、設定を削除します。",
+	"data.deleted":              "すべてのデータを削除しました (%d件の記録)",
+	"data.purge_prompt":         "ボタンを押すと、%s のすべてのデータを削除します。",
+	"data.purge_confirm_text":   "%s のすべての記録、お気に入り、設定とその履歴が削除されます。元に戻すことはできません。",
+	"data.purged":               "%s のすべてのデータを削除しました (%d件の記録)",
+	"data.delete_failed":        "データを削除できませんでした。時間をおいて再度お試しください",
+
+	"validation.drink_required":      "飲み物の種類を入力してください",
+	"validation.drink_too_long":      "飲み物の種類は%d文字以内で入力してください",
+	"validation.amount_required":     "摂取量を選択してください",
+	"validation.amount_range":        "摂取量は%dml から %dml の範囲で選択してください",
+	"validation.date_required":       "日付を選択してください",
+	"validation.time_required":       "時刻を選択してください",
+	"validation.time_future":         "未来の時刻は記録できません",
+	"validation.daily_goal_range":    "目標摂取量は%dml から %dml の範囲で入力してください",
+	"validation.post_mode_required":  "投稿先を選択してください",
+	"validation.post_channel_needed": "投稿先チャンネルを選択してください",
+	"validation.locale_required":     "言語を選択してください",
+
+	"report.title":   "週間水分摂取量",
+	"report.x_label": "曜日",
+	"report.y_label": "飲んだ量",
+	"report.caption": "過去一週間の水分摂取量を報告します",
+}
@@ -0,0 +1,57 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Supported locales.
+const (
+	Japanese      = "ja"
+	English       = "en"
+	DefaultLocale = Japanese
+)
+
+// catalogs maps locale to messages keyed by message key.
+var catalogs = map[string]map[string]string{
+	Japanese: ja,
+	English:  en,
+}
+
+// Normalize returns supported locale for locale such as Slack's "en-US".
+// Empty string is returned for unsupported locales.
+func Normalize(locale string) string {
+	language := strings.ToLower(strings.SplitN(strings.Replace(locale, "_", "-", 1), "-", 2)[0])
+	if _, ok := catalogs[language]; ok {
+		return language
+	}
+	return ""
+}
+
+// IsSupported returns true if locale has catalog.
+func IsSupported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Locales returns supported locales.
+func Locales() []string {
+	return []string{Japanese, English}
+}
+
+// T returns message of key in locale formatted with args.
+// Messages missing in locale fall back to default locale, then to key itself.
+func T(locale string, key string, args ...interface{}) string {
+	message, ok := catalogs[locale][key]
+	if !ok {
+		message, ok = catalogs[DefaultLocale][key]
+	}
+	if !ok {
+		return key
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
@@ -0,0 +1,134 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/i18n"
+)
+
+// Identifiers of water samples in Apple Health export.
+const (
+	appleWaterType   = "HKQuantityTypeIdentifierDietaryWater"
+	appleFoodTypeKey = "HKFoodType"
+	appleTimeLayout  = "2006-01-02 15:04:05 -0700"
+)
+
+// appleUnits maps units of Apple Health samples to units of mapping.
+var appleUnits = map[string]string{
+	"mL":        "ml",
+	"cL":        "cl",
+	"dL":        "dl",
+	"L":         "l",
+	"fl_oz_us":  "oz",
+	"fl_oz_imp": "oz_imp",
+	"cup_us":    "cup",
+}
+
+type (
+	// appleRecord describes Record element of Apple Health export.
+	appleRecord struct {
+		Type      string          `xml:"type,attr"`
+		Unit      string          `xml:"unit,attr"`
+		StartDate string          `xml:"startDate,attr"`
+		Value     string          `xml:"value,attr"`
+		Metadata  []appleMetadata `xml:"MetadataEntry"`
+	}
+
+	// appleMetadata describes MetadataEntry element of Apple Health export.
+	appleMetadata struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:"value,attr"`
+	}
+)
+
+// readAppleHealth reads water samples of export.xml of Apple Health one element at a time, as the file holds all
+// kinds of health data and may be large. Food type of sample is read as drink, and Number of row is line of sample.
+func readAppleHealth(r io.Reader, mapping Mapping, locale string, now time.Time) ([]Row, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+
+	var rows []Row
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed reading XML: %v", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Record" || attr(start, "type") != appleWaterType {
+			continue
+		}
+
+		line, _ := decoder.InputPos()
+		var record appleRecord
+		if err := decoder.DecodeElement(&record, &start); err != nil {
+			return nil, fmt.Errorf("Failed reading XML: %v", err)
+		}
+
+		unit, ok := appleUnits[record.Unit]
+		if !ok {
+			rows = append(rows, Row{
+				Number:  line,
+				Status:  StatusInvalid,
+				Problem: i18n.T(locale, "import.unit_invalid", record.Unit),
+			})
+			continue
+		}
+
+		sampleMapping := Mapping{
+			Drink:        "drink",
+			Amount:       "value",
+			DrankAt:      "startDate",
+			Layout:       appleTimeLayout,
+			Unit:         unit,
+			DefaultDrink: defaultDrink(mapping, locale),
+		}
+		rows = append(rows, sampleMapping.row(line, func(name string) string {
+			switch name {
+			case "drink":
+				return record.foodType()
+			case "value":
+				return record.Value
+			case "startDate":
+				return record.StartDate
+			}
+			return ""
+		}, time.Local, locale, now))
+	}
+
+	return rows, nil
+}
+
+// attr returns value of attribute of element, or empty string if it is not set.
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// foodType returns food type in metadata of sample, which is set by apps logging drinks other than water.
+func (record appleRecord) foodType() string {
+	for _, metadata := range record.Metadata {
+		if metadata.Key == appleFoodTypeKey {
+			return metadata.Value
+		}
+	}
+	return ""
+}
+
+// defaultDrink returns drink of records in formats without drink, which is water unless mapping names it.
+func defaultDrink(mapping Mapping, locale string) string {
+	if len(mapping.DefaultDrink) > 0 {
+		return mapping.DefaultDrink
+	}
+	return i18n.T(locale, "import.water")
+}
@@ -0,0 +1,65 @@
+package importer
+
+import "time"
+
+// googleFitDataType is data type of hydration in Google Fit. Its values are liters.
+const googleFitDataType = "com.google.hydration"
+
+// googleFitPoints returns data points of Google Fit data decoded from JSON.
+// Takeout files have "Data Points" with "fitValue", and datasets of Fitness API have "point" with "value".
+func googleFitPoints(root interface{}) ([]interface{}, bool) {
+	object, ok := root.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	for _, key := range []string{"Data Points", "point"} {
+		if points, ok := object[key].([]interface{}); ok {
+			return points, true
+		}
+	}
+	return nil, false
+}
+
+// readGoogleFit reads hydration data points of Google Fit. Points of other data types are skipped,
+// and Number of row is position of point counted from 1.
+func readGoogleFit(points []interface{}, mapping Mapping, locale string, now time.Time) []Row {
+	pointMapping := Mapping{
+		Amount:       "value",
+		DrankAt:      "startTimeNanos",
+		Layout:       LayoutUnixNano,
+		Unit:         "l",
+		DefaultDrink: defaultDrink(mapping, locale),
+	}
+
+	var rows []Row
+	for i, point := range points {
+		dataType := jsonString(lookup(point, "dataTypeName"))
+		if len(dataType) > 0 && dataType != googleFitDataType {
+			continue
+		}
+
+		rows = append(rows, pointMapping.row(i+1, func(name string) string {
+			switch name {
+			case "value":
+				return googleFitValue(point)
+			case "startTimeNanos":
+				return jsonString(lookup(point, "startTimeNanos"))
+			}
+			return ""
+		}, time.Local, locale, now))
+	}
+
+	return rows
+}
+
+// googleFitValue returns floating point value of data point in Takeout or Fitness API format.
+func googleFitValue(point interface{}) string {
+	if values, ok := lookup(point, "fitValue").([]interface{}); ok && len(values) > 0 {
+		return jsonString(lookup(values[0], "value.fpVal"))
+	}
+	if values, ok := lookup(point, "value").([]interface{}); ok && len(values) > 0 {
+		return jsonString(lookup(values[0], "fpVal"))
+	}
+	return ""
+}
@@ -0,0 +1,590 @@
+// Package importer reads hydration history exported from this bot or other trackers,
+// and adds records which are not recorded yet.
+package importer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/i18n"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/interfaces"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/validation"
+)
+
+// Formats of imported files.
+const (
+	FormatCSV         = "csv"
+	FormatJSON        = "json"
+	FormatAppleHealth = "apple_health"
+	FormatGoogleFit   = "google_fit"
+)
+
+// Statuses of imported rows.
+const (
+	StatusNew       = "new"
+	StatusAdded     = "added"
+	StatusDuplicate = "duplicate"
+	StatusInvalid   = "invalid"
+)
+
+// BatchSize is number of records inserted in one transaction.
+const BatchSize = 500
+
+// MaxFileSize is size limit of files uploaded to Slack for import.
+const MaxFileSize = 5 << 20
+
+// Layouts of timestamps given to layout of mapping, which are read as seconds, milliseconds or nanoseconds since epoch.
+const (
+	LayoutUnix      = "unix"
+	LayoutUnixMilli = "unix_ms"
+	LayoutUnixNano  = "unix_ns"
+)
+
+// epochUnits are numbers of units of epoch layouts in one second.
+var epochUnits = map[string]float64{
+	LayoutUnix:      1,
+	LayoutUnixMilli: 1e3,
+	LayoutUnixNano:  1e9,
+}
+
+// layouts are tried in order when mapping has no layout.
+var layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+}
+
+// mlPerUnit converts amounts in units to milliliters.
+var mlPerUnit = map[string]float64{
+	"ml":     1,
+	"cl":     10,
+	"dl":     100,
+	"l":      1000,
+	"oz":     29.5735,
+	"oz_imp": 28.4131,
+	"cup":    236.588,
+}
+
+type (
+	// Mapping names CSV columns or JSON keys which hold values of records.
+	// Date and Time are used instead of DrankAt when date and time are in separate columns.
+	// JSON keys may be dotted paths such as "volume.value", and Records is path of array of records
+	// when root of JSON is not array. Layout is Go time layout of timestamps, and timestamps without
+	// offset are read in time zone of user. Amounts are converted from Unit to milliliters.
+	// DefaultDrink is used when record has no drink.
+	Mapping struct {
+		Drink        string
+		Amount       string
+		DrankAt      string
+		Date         string
+		Time         string
+		Layout       string
+		Unit         string
+		Records      string
+		DefaultDrink string
+	}
+
+	// Row describes one record of imported file.
+	// Number is line number of CSV row or position of JSON record counted from 1.
+	// Problem describes why row is invalid.
+	Row struct {
+		Number    int
+		Status    string
+		Hydration models.Hydration
+		Problem   string
+	}
+
+	// Summary counts rows of each status.
+	Summary struct {
+		New        int
+		Duplicates int
+		Invalid    int
+	}
+)
+
+// DefaultMapping reads files exported from this bot.
+var DefaultMapping = Mapping{
+	Drink:   "drink",
+	Amount:  "amount",
+	DrankAt: "drank_at",
+	Unit:    "ml",
+}
+
+// IsFormat returns true if format is supported.
+func IsFormat(format string) bool {
+	switch format {
+	case FormatCSV, FormatJSON, FormatAppleHealth, FormatGoogleFit:
+		return true
+	}
+	return false
+}
+
+// FormatOf returns format of file from its extension, or empty string if it is not supported.
+// XML files are read as Apple Health export, and Google Fit data in JSON files is found by its content.
+func FormatOf(fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	case ".xml":
+		return FormatAppleHealth
+	}
+	return ""
+}
+
+// ParseMapping returns DefaultMapping overridden by spec such as `drink= amount="Volume (oz)" unit=oz default_drink=Water`.
+// Values containing spaces are quoted with double quotes, and empty value unsets key.
+func ParseMapping(spec string) (Mapping, error) {
+	mapping := DefaultMapping
+
+	fields, err := splitFields(spec)
+	if err != nil {
+		return mapping, err
+	}
+
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return mapping, fmt.Errorf("Mapping must be key=value: %s", field)
+		}
+
+		switch key {
+		case "drink":
+			mapping.Drink = value
+		case "amount":
+			mapping.Amount = value
+		case "drank_at":
+			mapping.DrankAt = value
+		case "date":
+			mapping.Date = value
+		case "time":
+			mapping.Time = value
+		case "layout":
+			mapping.Layout = value
+		case "unit":
+			value = strings.ToLower(value)
+			if _, ok := mlPerUnit[value]; !ok {
+				return mapping, fmt.Errorf("Unknown unit: %s", value)
+			}
+			mapping.Unit = value
+		case "records":
+			mapping.Records = value
+		case "default_drink":
+			mapping.DefaultDrink = value
+		default:
+			return mapping, fmt.Errorf("Unknown mapping key: %s", key)
+		}
+	}
+
+	if len(mapping.Amount) == 0 {
+		return mapping, errors.New("Mapping of amount is required")
+	}
+	if len(mapping.DrankAt) == 0 && len(mapping.Date) == 0 {
+		return mapping, errors.New("Mapping of drank_at or date is required")
+	}
+
+	return mapping, nil
+}
+
+// splitFields splits spec by spaces except in double quotes, and removes the quotes.
+func splitFields(spec string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inField := false
+	quoted := false
+
+	for _, r := range spec {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inField = true
+		case !quoted && (r == ' ' || r == '\t' || r == '\n'):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteRune(r)
+			inField = true
+		}
+	}
+
+	if quoted {
+		return nil, errors.New("Mapping has unclosed quote")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+
+	return fields, nil
+}
+
+// Read reads records of file in format and returns them as rows, which are new or invalid.
+// Timestamps without offset are read in loc, and problems of invalid rows are written in locale.
+// Error is returned only if file cannot be read as a whole.
+func Read(r io.Reader, format string, mapping Mapping, loc *time.Location, locale string, now time.Time) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(r, mapping, loc, locale, now)
+	case FormatJSON, FormatGoogleFit:
+		return readJSON(r, format, mapping, loc, locale, now)
+	case FormatAppleHealth:
+		return readAppleHealth(r, mapping, locale, now)
+	}
+	return nil, fmt.Errorf("Unknown import format: %s", format)
+}
+
+// readCSV reads rows of CSV file whose first line is header.
+func readCSV(r io.Reader, mapping Mapping, loc *time.Location, locale string, now time.Time) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("Failed reading CSV header: %v", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	for _, name := range []string{mapping.Drink, mapping.Amount, mapping.DrankAt, mapping.Date, mapping.Time} {
+		if _, ok := columns[name]; len(name) > 0 && !ok {
+			return nil, fmt.Errorf("Column not found in CSV header: %s", name)
+		}
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed reading CSV: %v", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, mapping.row(line, func(name string) string {
+			i, ok := columns[name]
+			if len(name) == 0 || !ok || i >= len(record) {
+				return ""
+			}
+			return record[i]
+		}, loc, locale, now))
+	}
+
+	return rows, nil
+}
+
+// readJSON reads rows of JSON file whose root or value at Records of mapping is array of objects.
+// Google Fit data is read as such unless Records of mapping is set.
+func readJSON(r io.Reader, format string, mapping Mapping, loc *time.Location, locale string, now time.Time) ([]Row, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var root interface{}
+	if err := decoder.Decode(&root); err != nil {
+		return nil, fmt.Errorf("Failed reading JSON: %v", err)
+	}
+
+	if points, ok := googleFitPoints(root); ok && len(mapping.Records) == 0 {
+		return readGoogleFit(points, mapping, locale, now), nil
+	}
+	if format == FormatGoogleFit {
+		return nil, errors.New("Data points of Google Fit not found in JSON")
+	}
+
+	value := root
+	if len(mapping.Records) > 0 {
+		value = lookup(root, mapping.Records)
+	}
+
+	records, ok := value.([]interface{})
+	if !ok {
+		if len(mapping.Records) > 0 {
+			return nil, fmt.Errorf("Array of records not found in JSON: %s", mapping.Records)
+		}
+		return nil, errors.New("Root of JSON is not array of records. Set records of mapping to key of the array")
+	}
+
+	var rows []Row
+	for i, record := range records {
+		rows = append(rows, mapping.row(i+1, func(path string) string {
+			if len(path) == 0 {
+				return ""
+			}
+			return jsonString(lookup(record, path))
+		}, loc, locale, now))
+	}
+
+	return rows, nil
+}
+
+// lookup returns value at dotted path of JSON value, or nil if not found.
+func lookup(value interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// jsonString returns JSON string or number as string, and empty string for other values.
+func jsonString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+// row returns row read from values of record at number, which are taken by field from mapped names.
+func (mapping Mapping) row(number int, field func(name string) string, loc *time.Location, locale string, now time.Time) Row {
+	row := Row{Number: number, Status: StatusNew}
+	var problems []string
+
+	form := validation.HydrationForm{
+		Drink:  field(mapping.Drink),
+		Locale: locale,
+	}
+	if len(strings.TrimSpace(form.Drink)) == 0 {
+		form.Drink = mapping.DefaultDrink
+	}
+
+	rawAmount := field(mapping.Amount)
+	amount, amountErr := mapping.amount(rawAmount)
+	if amountErr != nil {
+		problems = append(problems, i18n.T(locale, "import.amount_invalid", rawAmount))
+	} else {
+		form.Amount = strconv.FormatInt(amount, 10)
+	}
+
+	rawDrankAt := field(mapping.DrankAt)
+	if len(mapping.Date) > 0 {
+		rawDrankAt = strings.TrimSpace(field(mapping.Date) + " " + field(mapping.Time))
+	}
+
+	drankAt, timeErr := mapping.drankAt(rawDrankAt, loc)
+	if timeErr != nil {
+		problems = append(problems, i18n.T(locale, "import.time_invalid", rawDrankAt))
+	} else {
+		drankAt = drankAt.In(now.Location()).Truncate(time.Second)
+		form.DrankDate = drankAt.Format(validation.DateFormat)
+		form.DrankTime = drankAt.Format(validation.TimeFormat)
+	}
+
+	// errors of values which cannot be read are not shown again.
+	blocks := []string{validation.BlockDrink}
+	if amountErr == nil {
+		blocks = append(blocks, validation.BlockAmount)
+	}
+	if timeErr == nil {
+		blocks = append(blocks, validation.BlockDrankDate, validation.BlockDrankTime)
+	}
+
+	// amount is checked against validation.MinAmount and validation.MaxAmount in milliliters, same as record form.
+	hydration, errs := form.Validate(now)
+	for _, block := range blocks {
+		if message, ok := errs[block]; ok {
+			problems = append(problems, message)
+		}
+	}
+
+	hydration.DrankAt = drankAt
+	hydration.UpdatedAt = now
+	row.Hydration = hydration
+
+	if len(problems) > 0 {
+		row.Status = StatusInvalid
+		row.Problem = strings.Join(problems, ", ")
+	}
+
+	return row
+}
+
+// amount returns amount in milliliters from value in unit of mapping.
+func (mapping Mapping) amount(value string) (int64, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, err
+	}
+
+	unit := mapping.Unit
+	if len(unit) == 0 {
+		unit = "ml"
+	}
+
+	return int64(math.Round(amount * mlPerUnit[unit])), nil
+}
+
+// drankAt returns time of value in layout of mapping. Times without offset are read in loc.
+func (mapping Mapping) drankAt(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	switch mapping.Layout {
+	case LayoutUnix, LayoutUnixMilli, LayoutUnixNano:
+		epoch, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec, frac := math.Modf(epoch / epochUnits[mapping.Layout])
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	case "":
+		for _, layout := range layouts {
+			if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("Unknown time format: %s", value)
+	}
+
+	return time.ParseInLocation(mapping.Layout, value, loc)
+}
+
+// Prepare sets user of rows and marks new rows as duplicates if record of same time and amount
+// is already recorded for user or appears earlier in rows.
+func Prepare(repo interfaces.HydrationRepository, rows []Row, userName string) error {
+	var from, to time.Time
+	for i := range rows {
+		rows[i].Hydration.Username = userName
+
+		if rows[i].Status != StatusNew {
+			continue
+		}
+		drankAt := rows[i].Hydration.DrankAt
+		if from.IsZero() || drankAt.Before(from) {
+			from = drankAt
+		}
+		if to.IsZero() || !drankAt.Before(to) {
+			to = drankAt.Add(time.Second)
+		}
+	}
+
+	if from.IsZero() {
+		return nil
+	}
+
+	existing, err := repo.FetchHydrations(models.HydrationFilter{
+		Username: userName,
+		From:     from,
+		To:       to,
+	})
+	if err != nil {
+		return err
+	}
+
+	recorded := map[string]bool{}
+	for _, hydration := range existing {
+		recorded[duplicateKey(hydration)] = true
+	}
+
+	for i := range rows {
+		if rows[i].Status != StatusNew {
+			continue
+		}
+
+		key := duplicateKey(rows[i].Hydration)
+		if recorded[key] {
+			rows[i].Status = StatusDuplicate
+			continue
+		}
+		recorded[key] = true
+	}
+
+	return nil
+}
+
+// duplicateKey returns key which is same among records of same time and amount.
+// Times are saved without time zone, so wall clock is compared.
+func duplicateKey(hydration models.Hydration) string {
+	return hydration.DrankAt.Format("2006-01-02 15:04:05") + " " + strconv.FormatInt(hydration.Amount, 10)
+}
+
+// Apply inserts new rows in batches of BatchSize, marks them as added and returns number of inserted records.
+// Batches inserted before error are kept.
+func Apply(repo interfaces.HydrationRepository, rows []Row, actor models.Actor) (int64, error) {
+	var inserted int64
+	var batch []int
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		hydrationList := make([]models.Hydration, len(batch))
+		for i, rowIndex := range batch {
+			hydrationList[i] = rows[rowIndex].Hydration
+		}
+
+		count, err := repo.AddBatch(hydrationList, actor)
+		if err != nil {
+			return err
+		}
+
+		for _, rowIndex := range batch {
+			rows[rowIndex].Status = StatusAdded
+		}
+		inserted += count
+		batch = batch[:0]
+		return nil
+	}
+
+	for i, row := range rows {
+		if row.Status != StatusNew {
+			continue
+		}
+
+		batch = append(batch, i)
+		if len(batch) == BatchSize {
+			if err := flush(); err != nil {
+				return inserted, err
+			}
+		}
+	}
+
+	return inserted, flush()
+}
+
+// Summarize counts rows of each status.
+func Summarize(rows []Row) Summary {
+	var summary Summary
+	for _, row := range rows {
+		switch row.Status {
+		case StatusNew:
+			summary.New++
+		case StatusDuplicate:
+			summary.Duplicates++
+		case StatusInvalid:
+			summary.Invalid++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,141 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/i18n"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/validation"
+)
+
+// samplesDirPath is directory of sample files checked into repository.
+var samplesDirPath = filepath.Join("..", "..", "..", "configs", "samples")
+
+// sample describes record expected from sample file.
+type sample struct {
+	drink   string
+	amount  int64
+	drankAt string
+}
+
+// readSample reads sample file in format as it is read from file shared without mapping.
+func readSample(t *testing.T, fileName string, format string) []Row {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join(samplesDirPath, fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := Read(file, format, DefaultMapping, time.UTC, i18n.English, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+// checkRows fails test unless rows are new records matching samples.
+func checkRows(t *testing.T, rows []Row, samples []sample) {
+	t.Helper()
+
+	if len(rows) != len(samples) {
+		t.Fatalf("read %d rows, want %d: %+v", len(rows), len(samples), rows)
+	}
+
+	for i, want := range samples {
+		row := rows[i]
+		if row.Status != StatusNew {
+			t.Errorf("row %d: status = %s (%s), want %s", row.Number, row.Status, row.Problem, StatusNew)
+			continue
+		}
+
+		drankAt, err := time.Parse(time.RFC3339, want.drankAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hydration := row.Hydration
+		if hydration.Drink != want.drink || hydration.Amount != want.amount || !hydration.DrankAt.Equal(drankAt) {
+			t.Errorf("row %d: got %s %dml at %s, want %s %dml at %s", row.Number,
+				hydration.Drink, hydration.Amount, hydration.DrankAt.Format(time.RFC3339),
+				want.drink, want.amount, want.drankAt)
+		}
+	}
+}
+
+func TestReadAppleHealthSample(t *testing.T) {
+	rows := readSample(t, "apple_health_export.xml", FormatOf("apple_health_export.xml"))
+
+	checkRows(t, rows, []sample{
+		{"Water", 250, "2020-05-01T09:30:00+09:00"},
+		{"Green tea", 350, "2020-05-01T12:15:00+09:00"},
+		{"Water", 237, "2020-05-02T15:00:00+09:00"},
+		{"Water", 500, "2020-05-03T08:00:00+09:00"},
+	})
+}
+
+func TestReadGoogleFitSample(t *testing.T) {
+	samples := []sample{
+		{"Water", 250, "2020-05-01T09:30:00+09:00"},
+		{"Water", 350, "2020-05-01T12:15:00+09:00"},
+		{"Water", 500, "2020-05-03T08:00:00+09:00"},
+	}
+
+	for _, format := range []string{FormatOf("google_fit_hydration.json"), FormatGoogleFit} {
+		t.Run(format, func(t *testing.T) {
+			checkRows(t, readSample(t, "google_fit_hydration.json", format), samples)
+		})
+	}
+}
+
+func TestReadAppleHealthUnknownUnit(t *testing.T) {
+	const xml = `<HealthData><Record type="HKQuantityTypeIdentifierDietaryWater" unit="gal" startDate="2020-05-01 09:30:00 +0900" value="1"/></HealthData>`
+
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := readAppleHealth(strings.NewReader(xml), DefaultMapping, i18n.English, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Status != StatusInvalid {
+		t.Fatalf("rows = %+v, want one invalid row", rows)
+	}
+}
+
+func TestReadCSVAmountRange(t *testing.T) {
+	const csv = "drink,amount,drank_at\n" +
+		"Water,2000,2020-05-01 09:30\n" +
+		"Water,2001,2020-05-01 10:30\n" +
+		"Water,0,2020-05-01 11:30\n" +
+		"Water,2.1,2020-05-01 12:30\n"
+
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	mapping := DefaultMapping
+	rows, err := Read(strings.NewReader(csv), FormatCSV, mapping, time.UTC, i18n.English, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mapping.Unit = "l"
+	litreRows, err := Read(strings.NewReader(csv), FormatCSV, mapping, time.UTC, i18n.English, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rangeProblem := i18n.T(i18n.English, "validation.amount_range", validation.MinAmount, validation.MaxAmount)
+	for i, want := range []string{StatusNew, StatusInvalid, StatusInvalid, StatusNew} {
+		row := rows[i]
+		if row.Status != want {
+			t.Errorf("row %d: status = %s (%s), want %s", row.Number, row.Status, row.Problem, want)
+		}
+		if want == StatusInvalid && row.Problem != rangeProblem {
+			t.Errorf("row %d: problem = %q, want %q", row.Number, row.Problem, rangeProblem)
+		}
+	}
+	if litreRows[3].Status != StatusInvalid {
+		t.Errorf("2.1 l is imported as %dml", litreRows[3].Hydration.Amount)
+	}
+}
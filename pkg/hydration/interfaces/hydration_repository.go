@@ -1,6 +1,9 @@
 package interfaces
 
 import (
+	"context"
+	"time"
+
 	"github.com/pirosuke/slack-bot-hydration/internal/database"
 	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
 )
@@ -11,18 +14,85 @@ type HydrationRepository interface {
 	Connect(config database.DbConfig) error
 	// Close closes connection to database.
 	Close()
+	// Ping checks that database is reachable.
+	Ping() error
 	// Add inserts hydration data.
-	Add(hydration models.Hydration) (int64, error)
+	Add(hydration models.Hydration, actor models.Actor) (int64, error)
+	// AddBatch inserts hydration data in one transaction and returns number of inserted records.
+	// Revisions are not saved, so imported records are not reverted by undo.
+	AddBatch(hydrationList []models.Hydration, actor models.Actor) (int64, error)
 	// FetchOne returns one hydration data.
 	FetchOne(hydrationID int64) (models.Hydration, error)
 	// FetchDailyAmount returns summary of today's total drink amount.
 	FetchDailyAmount(userName string) (int64, error)
+	// FetchDailyStats returns numbers of records and users logged today.
+	FetchDailyStats() (models.DailyStats, error)
 	// FetchWeeklyUsers returns user list with hydration record during this week.
 	FetchWeeklyUsers() ([]string, error)
 	// FetchWeeklySummary returns summary of weekly hydration.
 	FetchWeeklySummary(userName string) ([]models.DailyHydrationSummary, error)
+	// FetchDailySummaries returns daily total amounts of last days including days without records.
+	FetchDailySummaries(userName string, days int) ([]models.DailyHydrationSummary, error)
+	// FetchRecent returns latest hydration data.
+	FetchRecent(userName string, limit int) ([]models.Hydration, error)
+	// FetchFrequentDrinks returns most frequent drink and amount pairs of user.
+	FetchFrequentDrinks(userName string, limit int) ([]models.FavoriteDrink, error)
+	// FetchPinnedDrinks returns drinks pinned by user.
+	FetchPinnedDrinks(userName string) ([]models.FavoriteDrink, error)
+	// PinDrink pins drink to favorites of user.
+	PinDrink(userName string, favorite models.FavoriteDrink) error
+	// UnpinDrink removes drink from favorites of user.
+	UnpinDrink(userName string, favorite models.FavoriteDrink) error
+	// FetchUserSettings returns user settings. Zero values are returned if user has no settings.
+	FetchUserSettings(userName string) (models.UserSettings, error)
+	// SaveUserSettings inserts or updates user settings.
+	SaveUserSettings(settings models.UserSettings) error
 	// Update updates hydration data.
-	Update(hydration models.Hydration) error
+	Update(hydration models.Hydration, actor models.Actor) error
 	// Delete deletes hydration data.
-	Delete(hydration models.Hydration) error
+	Delete(hydration models.Hydration, actor models.Actor) error
+	// SetMessage saves location of result message of hydration.
+	SetMessage(hydrationID int64, channel string, messageTS string) error
+	// FetchRevision returns one revision.
+	FetchRevision(revisionID int64) (models.HydrationRevision, error)
+	// FetchLastRevision returns latest revision of user which is not undone yet.
+	// Zero values are returned if there is no such revision.
+	FetchLastRevision(userName string) (models.HydrationRevision, error)
+	// FetchLastHydrationRevision returns latest revision of hydration which is not undone yet.
+	// Zero values are returned if there is no such revision.
+	FetchLastHydrationRevision(hydrationID int64) (models.HydrationRevision, error)
+	// Undo reverts operation of revision and returns reverted hydration.
+	Undo(revision models.HydrationRevision, actor models.Actor) (models.Hydration, error)
+	// FetchHydrations returns hydration data matched by filter in order of drank time.
+	FetchHydrations(filter models.HydrationFilter) ([]models.Hydration, error)
+	// EachHydration calls fn with each hydration data of user in order of drank time without loading all of them.
+	// Iteration stops at first error of fn, and the error is returned.
+	EachHydration(userName string, fn func(hydration models.Hydration) error) error
+	// FetchUserStats returns numbers and amounts of records matched by filter for each user.
+	FetchUserStats(filter models.HydrationFilter) ([]models.UserStats, error)
+	// MergeUsers moves hydration data including deleted records, revisions, favorites, settings and audit logs of user
+	// to another user and returns number of moved records which are not deleted. Settings are moved only if another user has none.
+	MergeUsers(fromUserName string, toUserName string, actor models.Actor) (int64, error)
+	// RenameDrink renames drink of records matched by filter and returns their number. Drink of filter is required.
+	RenameDrink(filter models.HydrationFilter, drink string, actor models.Actor) (int64, error)
+	// DeleteUserData deletes all data of user and returns number of deleted records. User is removed from audit logs,
+	// and audit log of the deletion is saved as models.AnonymizedUser.
+	DeleteUserData(userName string, actor models.Actor) (int64, error)
+	// PurgeBefore deletes records drunk before cutoff, or removes user from them if anonymize is true,
+	// and returns number of purged records. Audit log is saved for each user whose records are purged.
+	PurgeBefore(cutoff time.Time, anonymize bool, actor models.Actor) (int64, error)
+	// FetchAuditLogs returns latest audit logs matched by filter.
+	FetchAuditLogs(filter models.AuditLogFilter) ([]models.AuditLog, error)
+	// ClaimDelivery records Slack delivery for ttl and returns false if it is already recorded and not expired.
+	ClaimDelivery(deliveryID string, ttl time.Duration) (bool, error)
+	// ReleaseDelivery deletes record of Slack delivery so that its retry is handled.
+	ReleaseDelivery(deliveryID string) error
+	// DeleteExpiredDeliveries deletes expired records of Slack deliveries and returns their number.
+	DeleteExpiredDeliveries() (int64, error)
+}
+
+// ContextRepository is implemented by hydration repositories whose queries can be canceled with context.
+type ContextRepository interface {
+	// WithContext returns copy of repository whose queries are canceled with ctx.
+	WithContext(ctx context.Context) HydrationRepository
 }
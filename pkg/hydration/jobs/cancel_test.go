@@ -0,0 +1,53 @@
+package jobs_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/jobs"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/repositories"
+)
+
+func TestCancelStopsJobBlockedOnSlackCall(t *testing.T) {
+	release := make(chan struct{})
+	called := make(chan struct{})
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(called)
+		<-release
+	}))
+	defer slackServer.Close()
+	// handler is released before server is closed, as Close waits for it.
+	defer close(release)
+
+	// repository is bound to request context as in handlers, and job binds it to its own context.
+	requestCtx, cancelRequest := context.WithCancel(context.Background())
+	slackRepo := (&repositories.SlackRepository{}).WithContext(requestCtx)
+	cancelRequest()
+
+	runner := &jobs.Runner{}
+	errs := make(chan error, 1)
+	runner.Go(requestCtx, jobs.Describe("respond"), func(ctx context.Context) {
+		_, err := slackRepo.WithContext(ctx).Respond(slackServer.URL, "done", false)
+		errs <- err
+	})
+
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Slack call is not made, or is canceled with request")
+	}
+
+	runner.Cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := runner.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err == nil {
+		t.Error("canceled Slack call returned no error")
+	}
+}
@@ -0,0 +1,149 @@
+// Package jobs runs work which continues after responding to Slack.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/logging"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/metrics"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/tracing"
+)
+
+// Runner runs background jobs and tracks ones which are not finished.
+type Runner struct {
+	wg      sync.WaitGroup
+	pending atomic.Int64
+
+	mu      sync.Mutex
+	lastID  int64
+	running map[int64]*runningJob
+}
+
+// Info identifies job in logs. Args are key-value pairs summarizing payload of job, such as user and record ID.
+type Info struct {
+	Name string
+	Args []any
+}
+
+// runningJob describes job which is not finished.
+type runningJob struct {
+	info      Info
+	ctx       context.Context
+	cancel    context.CancelFunc
+	startedAt time.Time
+}
+
+// Describe returns info of job named name with key-value pairs args.
+func Describe(name string, args ...any) Info {
+	return Info{Name: name, Args: args}
+}
+
+// Go runs job in new goroutine. Unfinished jobs are counted in job queue depth metric.
+// Job gets ctx which keeps request ID and trace but is not canceled when response is sent, only by Cancel.
+// Job is traced as child span of span in ctx.
+func (runner *Runner) Go(ctx context.Context, info Info, job func(ctx context.Context)) {
+	ctx, span := tracing.Start(logging.Detach(ctx), "background job")
+	ctx, cancel := context.WithCancel(ctx)
+
+	metrics.JobQueued()
+	runner.pending.Add(1)
+	runner.wg.Add(1)
+	id := runner.add(&runningJob{info: info, ctx: ctx, cancel: cancel, startedAt: time.Now()})
+
+	go func() {
+		defer runner.wg.Done()
+		defer runner.pending.Add(-1)
+		defer runner.remove(id)
+		defer metrics.JobDone()
+		defer func() {
+			var err error
+			if r := recover(); r != nil {
+				err = fmt.Errorf("Panic in background job: %v", r)
+				slog.ErrorContext(ctx, "Panic in background job", "job", info.Name, "panic", fmt.Sprint(r))
+			}
+			tracing.End(span, err)
+		}()
+
+		job(ctx)
+	}()
+}
+
+// add registers job as running and returns its ID.
+func (runner *Runner) add(job *runningJob) int64 {
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+
+	if runner.running == nil {
+		runner.running = map[int64]*runningJob{}
+	}
+	runner.lastID++
+	runner.running[runner.lastID] = job
+	return runner.lastID
+}
+
+// remove unregisters finished job.
+func (runner *Runner) remove(id int64) {
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+
+	runner.running[id].cancel()
+	delete(runner.running, id)
+}
+
+// Pending returns number of jobs which are not finished.
+func (runner *Runner) Pending() int64 {
+	return runner.pending.Load()
+}
+
+// Cancel cancels contexts of jobs which are not finished.
+// Slack calls and queries of repositories given job context with WithContext are canceled, so jobs blocked on them return.
+func (runner *Runner) Cancel() {
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+
+	for _, job := range runner.running {
+		job.cancel()
+	}
+}
+
+// Wait waits until all jobs finish. If ctx is done first, each unfinished job is logged with its ID, name and args,
+// and error with number of unfinished jobs is returned.
+func (runner *Runner) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		runner.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		runner.logUnfinished()
+		return fmt.Errorf("%d background jobs are not finished: %v", runner.Pending(), ctx.Err())
+	}
+}
+
+// logUnfinished logs jobs which are not finished in order of start.
+func (runner *Runner) logUnfinished() {
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+
+	ids := make([]int64, 0, len(runner.running))
+	for id := range runner.running {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		job := runner.running[id]
+		args := append([]any{"job_id", id, "job", job.info.Name, "elapsed", time.Since(job.startedAt).Round(time.Millisecond)}, job.info.Args...)
+		slog.WarnContext(job.ctx, "Background job is not finished", args...)
+	}
+}
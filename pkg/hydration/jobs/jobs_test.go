@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitLogsAndCancelStopsUnfinishedJobs(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	runner := &Runner{}
+	runner.Go(context.Background(), Describe("finished job"), func(ctx context.Context) {})
+	runner.Go(context.Background(), Describe("add hydration", "user", "alice", "hydration_id", 42), func(ctx context.Context) {
+		<-ctx.Done()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := runner.Wait(ctx); err == nil {
+		t.Fatal("Wait returned nil while job is running")
+	}
+
+	output := logs.String()
+	for _, want := range []string{"Background job is not finished", "job=\"add hydration\"", "user=alice", "hydration_id=42"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("log does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "finished job") {
+		t.Errorf("finished job is logged:\n%s", output)
+	}
+
+	runner.Cancel()
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := runner.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if runner.Pending() != 0 {
+		t.Errorf("Pending() = %d, want 0", runner.Pending())
+	}
+}
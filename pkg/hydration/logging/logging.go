@@ -0,0 +1,175 @@
+// Package logging writes leveled JSON logs carrying request IDs, with tokens and message text redacted.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/tracing"
+)
+
+// Redacted replaces values which must not be logged.
+const Redacted = "[REDACTED]"
+
+// Outputs which are not file paths.
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+)
+
+type (
+	// requestIDKey is context key of request ID.
+	requestIDKey struct{}
+
+	// contextHandler adds request ID and trace ID in context to records.
+	contextHandler struct {
+		slog.Handler
+	}
+
+	nopCloser struct {
+		io.Writer
+	}
+)
+
+// redactedKeys are attribute keys whose values are always redacted.
+var redactedKeys = map[string]bool{
+	"token":         true,
+	"authorization": true,
+	"password":      true,
+	"secret":        true,
+	"text":          true,
+	"blocks":        true,
+	"payload":       true,
+	"response_url":  true,
+	"trigger_id":    true,
+}
+
+var (
+	// slackTokenPattern matches Slack tokens such as bot tokens.
+	slackTokenPattern = regexp.MustCompile(`xox[a-z]-[A-Za-z0-9-]+`)
+	// urlPasswordPattern matches user info of URLs such as DATABASE_URL.
+	urlPasswordPattern = regexp.MustCompile(`://[^/@\s:]+:[^/@\s]+@`)
+)
+
+// ParseLevel returns level named debug, info, warn or error. Empty name is info.
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+
+	return slog.LevelInfo, fmt.Errorf("Unknown log level: %s", name)
+}
+
+// OpenOutput returns stdout, stderr or file opened for appending.
+func OpenOutput(output string) (io.WriteCloser, error) {
+	switch output {
+	case "", OutputStdout:
+		return nopCloser{os.Stdout}, nil
+	case OutputStderr:
+		return nopCloser{os.Stderr}, nil
+	}
+
+	return os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+}
+
+// Close does nothing as standard streams are not closed.
+func (nopCloser) Close() error {
+	return nil
+}
+
+// New returns logger writing JSON records of level or above to w.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	})})
+}
+
+// Handle adds request ID and trace ID to record.
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); len(requestID) > 0 {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if traceID := tracing.TraceID(ctx); len(traceID) > 0 {
+		record.AddAttrs(slog.String("trace_id", traceID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs returns handler with attributes keeping request ID handling.
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns handler with group keeping request ID handling.
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// redact hides values of sensitive keys, and tokens and passwords in other strings.
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if redactedKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, Redacted)
+	}
+
+	switch value := attr.Value.Any().(type) {
+	case string:
+		return slog.String(attr.Key, Scrub(value))
+	case error:
+		return slog.String(attr.Key, Scrub(value.Error()))
+	}
+
+	return attr
+}
+
+// Scrub replaces Slack tokens and passwords of URLs in text.
+func Scrub(text string) string {
+	text = slackTokenPattern.ReplaceAllString(text, Redacted)
+	return urlPasswordPattern.ReplaceAllString(text, "://"+Redacted+"@")
+}
+
+// NewRequestID returns random request ID.
+func NewRequestID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// WithRequestID returns context carrying request ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns request ID carried by context.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// Detach returns context keeping values such as request ID but not canceled with ctx.
+// It is used for work which continues after response is sent.
+func Detach(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return context.WithoutCancel(ctx)
+}
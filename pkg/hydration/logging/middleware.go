@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+)
+
+// HeaderRequestID is header which carries request ID to and from clients.
+const HeaderRequestID = echo.HeaderXRequestID
+
+// Middleware assigns request ID to each request and logs handled requests.
+// Request ID in header is used if client sends one.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			requestID := c.Request().Header.Get(HeaderRequestID)
+			if len(requestID) == 0 || len(requestID) > 64 {
+				requestID = NewRequestID()
+			}
+			c.Response().Header().Set(HeaderRequestID, requestID)
+
+			ctx := WithRequestID(c.Request().Context(), requestID)
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			slog.Log(ctx, level, "Handled request",
+				"method", c.Request().Method,
+				"path", c.Path(),
+				"status", status,
+				"duration_ms", time.Since(start).Milliseconds(),
+			)
+
+			return nil
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Version is current version of metadata format.
+const Version = 1
+
+// Errors returned when metadata can not be trusted.
+var (
+	ErrMalformed = errors.New("metadata is malformed")
+	ErrSignature = errors.New("metadata signature does not match")
+	ErrVersion   = errors.New("metadata version is not supported")
+)
+
+// Metadata describes private_metadata of modals.
+// TimeZone is time zone of user which dates and times of form are shown in, such as "Asia/Tokyo".
+type Metadata struct {
+	Version     int    `json:"v"`
+	Channel     string `json:"channel,omitempty"`
+	MessageTS   string `json:"ts,omitempty"`
+	HydrationID int64  `json:"id,omitempty"`
+	TimeZone    string `json:"tz,omitempty"`
+}
+
+// Encode returns metadata signed with secret.
+func Encode(secret string, metadata Metadata) (string, error) {
+	metadata.Version = Version
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(metadataJSON)
+	return payload + "." + sign(secret, payload), nil
+}
+
+// Decode verifies signature with secret and returns metadata.
+func Decode(secret string, value string) (Metadata, error) {
+	var metadata Metadata
+
+	valueList := strings.Split(value, ".")
+	if len(valueList) != 2 {
+		return metadata, ErrMalformed
+	}
+
+	if !hmac.Equal([]byte(sign(secret, valueList[0])), []byte(valueList[1])) {
+		return metadata, ErrSignature
+	}
+
+	metadataJSON, err := base64.RawURLEncoding.DecodeString(valueList[0])
+	if err != nil {
+		return metadata, ErrMalformed
+	}
+
+	if err := json.Unmarshal(metadataJSON, &metadata); err != nil {
+		return metadata, ErrMalformed
+	}
+
+	if metadata.Version != Version {
+		return metadata, ErrVersion
+	}
+
+	return metadata, nil
+}
+
+// Location returns time zone of metadata. Server time zone is returned if it is not set or unknown.
+func (metadata Metadata) Location() *time.Location {
+	if len(metadata.TimeZone) == 0 {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(metadata.TimeZone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+func sign(secret string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,147 @@
+// Package metrics registers Prometheus metrics of the bot and serves them.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace is prefix of all metric names.
+const namespace = "hydration"
+
+// Outcomes used as outcome label.
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+)
+
+// ErrorTransport is error label of Slack API calls which did not get response.
+const ErrorTransport = "transport"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	interactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "interactions_total",
+		Help:      "Handled interactions by payload type, callback or action ID and outcome.",
+	}, []string{"payload_type", "callback_id", "outcome"})
+
+	interactionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "interaction_duration_seconds",
+		Help:      "Time taken to respond to interactions.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"payload_type", "callback_id"})
+
+	slackCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "slack_api_calls_total",
+		Help:      "Slack Web API calls by method and error code. Error is empty for successful calls.",
+	}, []string{"method", "error"})
+
+	slackCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "slack_api_call_duration_seconds",
+		Help:      "Time taken by Slack Web API calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "repository_query_duration_seconds",
+		Help:      "Time taken by repository methods by outcome.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"method", "outcome"})
+
+	duplicateDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "duplicate_deliveries_total",
+		Help:      "Slack deliveries acknowledged without handling as they were already received.",
+	}, []string{"payload_type"})
+
+	jobQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "job_queue_depth",
+		Help:      "Background jobs which are queued or running.",
+	})
+
+	recordsToday = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "records_today",
+		Help:      "Drinks logged today.",
+	})
+
+	activeUsers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_users",
+		Help:      "Users who logged drinks today.",
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		interactions,
+		interactionDuration,
+		slackCalls,
+		slackCallDuration,
+		queryDuration,
+		duplicateDeliveries,
+		jobQueueDepth,
+		recordsToday,
+		activeUsers,
+	)
+}
+
+// Handler serves registered metrics in Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveInteraction records handled interaction.
+func ObserveInteraction(payloadType string, callbackID string, outcome string, elapsed time.Duration) {
+	interactions.WithLabelValues(payloadType, callbackID, outcome).Inc()
+	interactionDuration.WithLabelValues(payloadType, callbackID).Observe(elapsed.Seconds())
+}
+
+// ObserveSlackCall records Slack Web API call. errorCode is empty for successful calls.
+func ObserveSlackCall(method string, errorCode string, elapsed time.Duration) {
+	slackCalls.WithLabelValues(method, errorCode).Inc()
+	slackCallDuration.WithLabelValues(method).Observe(elapsed.Seconds())
+}
+
+// ObserveQuery records call of repository method.
+func ObserveQuery(method string, err error, elapsed time.Duration) {
+	outcome := OutcomeOK
+	if err != nil {
+		outcome = OutcomeError
+	}
+	queryDuration.WithLabelValues(method, outcome).Observe(elapsed.Seconds())
+}
+
+// ObserveDuplicateDelivery records Slack delivery which was already received.
+func ObserveDuplicateDelivery(payloadType string) {
+	duplicateDeliveries.WithLabelValues(payloadType).Inc()
+}
+
+// JobQueued records that background job is queued.
+func JobQueued() {
+	jobQueueDepth.Inc()
+}
+
+// JobDone records that background job finished.
+func JobDone() {
+	jobQueueDepth.Dec()
+}
+
+// SetDailyStats sets gauges of today's records and users.
+func SetDailyStats(records int64, users int64) {
+	recordsToday.Set(float64(records))
+	activeUsers.Set(float64(users))
+}
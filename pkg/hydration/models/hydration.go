@@ -1,15 +1,68 @@
 package models
 
-import "time"
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operations saved in hydration revisions.
+const (
+	OperationAdd    = "add"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// Sources of changes saved in audit logs.
+const (
+	SourceModal     = "modal"
+	SourceButton    = "button"
+	SourceCommand   = "command"
+	SourceAPI       = "api"
+	SourceCLI       = "cli"
+	SourceImport    = "import"
+	SourceRetention = "retention"
+)
+
+// ActionPurge is action of audit logs saved when data of user is purged.
+// Before of the audit log has number of purged records instead of hydration values.
+const ActionPurge = "purge"
+
+// AnonymizedUser is user name of purge audit logs of records which were anonymized before they were purged,
+// and of audit logs of deletion of all data of user.
+// It is not a valid Slack user name, so it is not mixed up with real users.
+const AnonymizedUser = "(anonymized)"
+
+// MaxFavoriteDrinks is number of favorite drinks shown as quick logging buttons.
+// Users pin up to this many drinks, so pinned drinks are always shown.
+const MaxFavoriteDrinks = 5
 
 type (
 	// Hydration describes hydration data.
 	Hydration struct {
-		ID       int64
-		Username string
-		Drink    string
-		Amount   int64
-		Modified time.Time
+		ID        int64
+		Username  string
+		Drink     string
+		Amount    int64
+		DrankAt   time.Time
+		UpdatedAt time.Time
+		Channel   string
+		MessageTS string
+	}
+
+	// HydrationRevision describes hydration values before operation.
+	HydrationRevision struct {
+		ID          int64
+		HydrationID int64
+		Username    string
+		Operation   string
+		Drink       string
+		Amount      int64
+		DrankAt     time.Time
+		CreatedAt   time.Time
+		// Age is time elapsed since operation, measured by clock of database which wrote CreatedAt.
+		Age    time.Duration
+		Undone bool
 	}
 
 	// DailyHydrationSummary describes daily total amount of drinks
@@ -17,4 +70,136 @@ type (
 		Day         string
 		TotalAmount int64
 	}
+
+	// DailyStats describes records logged today by all users.
+	DailyStats struct {
+		Records int64
+		Users   int64
+	}
+
+	// HydrationFilter describes conditions for searching hydration data.
+	// Zero values are ignored. Records drunk at From or later and before To are matched.
+	HydrationFilter struct {
+		Username string
+		Drink    string
+		From     time.Time
+		To       time.Time
+		Limit    int
+	}
+
+	// UserStats describes records of user matched by filter.
+	UserStats struct {
+		Username     string
+		Records      int64
+		TotalAmount  int64
+		Days         int64
+		FirstDrankAt time.Time
+		LastDrankAt  time.Time
+	}
+
+	// Actor describes who changes hydration data and from where.
+	Actor struct {
+		Username string
+		Source   string
+	}
+
+	// AuditLog describes one change of hydration data.
+	// Before and After are JSON of hydration values and empty if record does not exist.
+	AuditLog struct {
+		ID          int64
+		Actor       string
+		Action      string
+		HydrationID int64
+		Username    string
+		Before      string
+		After       string
+		Source      string
+		CreatedAt   time.Time
+	}
+
+	// AuditLogFilter describes conditions for searching audit logs.
+	// Zero values are ignored.
+	AuditLogFilter struct {
+		Username    string
+		HydrationID int64
+		Limit       int
+	}
+
+	// FavoriteDrink describes drink and amount pair used for quick logging.
+	FavoriteDrink struct {
+		Drink  string
+		Amount int64
+		Count  int64
+		Pinned bool
+	}
+
+	// UserSettings describes per user settings.
+	// PostMode and PostChannel are empty if user follows workspace routing.
+	UserSettings struct {
+		Username    string
+		UserID      string
+		DailyGoal   int64
+		PostMode    string
+		PostChannel string
+		Locale      string
+	}
+
+	// HydrationDashboard describes data shown on App Home.
+	HydrationDashboard struct {
+		DailyAmount      int64
+		DailyGoal        int64
+		DailySummaries   []DailyHydrationSummary
+		RecentHydrations []Hydration
+		FavoriteDrinks   []FavoriteDrink
+		UndoRevision     HydrationRevision
+	}
 )
+
+// Key returns value which identifies favorite drink in button actions.
+func (favorite FavoriteDrink) Key() string {
+	return strconv.FormatInt(favorite.Amount, 10) + ":" + favorite.Drink
+}
+
+// ParseFavoriteDrinkKey returns favorite drink from value created by Key.
+func ParseFavoriteDrinkKey(key string) (FavoriteDrink, bool) {
+	keyList := strings.SplitN(key, ":", 2)
+	if len(keyList) != 2 {
+		return FavoriteDrink{}, false
+	}
+
+	amount, err := strconv.ParseInt(keyList[0], 10, 64)
+	if err != nil {
+		return FavoriteDrink{}, false
+	}
+
+	return FavoriteDrink{
+		Drink:  keyList[1],
+		Amount: amount,
+	}, true
+}
+
+// MergeFavoriteDrinks returns pinned drinks followed by frequent drinks which are not pinned, up to limit.
+// Pins are capped at MaxFavoriteDrinks when pinning, so pinned drinks are not dropped with limit of MaxFavoriteDrinks.
+func MergeFavoriteDrinks(pinned []FavoriteDrink, frequent []FavoriteDrink, limit int) []FavoriteDrink {
+	favorites := pinned
+
+	for _, frequentDrink := range frequent {
+		isPinned := false
+		for _, favorite := range pinned {
+			if favorite.Key() == frequentDrink.Key() {
+				isPinned = true
+				break
+			}
+		}
+
+		if !isPinned {
+			favorites = append(favorites, frequentDrink)
+		}
+	}
+
+	if len(favorites) > limit {
+		favorites = favorites[:limit]
+	}
+
+	return favorites
+}
@@ -2,61 +2,184 @@ package repositories
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	pgx "github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/pirosuke/slack-bot-hydration/internal/database"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/interfaces"
 	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
 )
 
 // HydrationPgRepository is gateway for PostgreSQL database repository.
 type HydrationPgRepository struct {
-	conn *pgx.Conn
+	conn *pgxpool.Pool
+
+	ctx context.Context
+}
+
+// WithContext returns copy of repository whose queries are canceled with ctx.
+func (repo *HydrationPgRepository) WithContext(ctx context.Context) interfaces.HydrationRepository {
+	withContext := *repo
+	withContext.ctx = ctx
+	return &withContext
+}
+
+// context returns context of queries.
+func (repo *HydrationPgRepository) context() context.Context {
+	if repo.ctx == nil {
+		return context.Background()
+	}
+	return repo.ctx
+}
+
+// queryRower is implemented by both connection pool and transaction.
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// queryer is implemented by both connection pool and transaction.
+type queryer interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
 }
 
 // Connect connects to database.
 func (repo *HydrationPgRepository) Connect(config database.DbConfig) error {
 	var err error
-	dbURL := "postgres://" + config.Connection.User + ":" + config.Connection.Password + "@" + config.Connection.Host + ":" + strconv.FormatInt(config.Connection.Port, 10) + "/" + config.Connection.Database
-	repo.conn, err = pgx.Connect(context.Background(), dbURL)
+	dbURL := config.URL
+	if len(dbURL) == 0 {
+		dbURL = (&url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(config.Connection.User, config.Connection.Password),
+			Host:   config.Connection.Host + ":" + strconv.FormatInt(config.Connection.Port, 10),
+			Path:   "/" + config.Connection.Database,
+		}).String()
+	}
+	repo.conn, err = pgxpool.Connect(context.Background(), dbURL)
 	return err
 }
 
+// pingTimeout is time limit of Ping.
+const pingTimeout = 5 * time.Second
+
 // Close closes connection to database.
 func (repo *HydrationPgRepository) Close() {
-	repo.conn.Close(context.Background())
+	repo.conn.Close()
+}
+
+// Ping checks that database is reachable.
+func (repo *HydrationPgRepository) Ping() error {
+	ctx, cancel := context.WithTimeout(repo.context(), pingTimeout)
+	defer cancel()
+
+	conn, err := repo.conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	return conn.Conn().Ping(ctx)
 }
 
 // Add inserts hydration data.
-func (repo *HydrationPgRepository) Add(hydration models.Hydration) (int64, error) {
-	var hydrationID int64
-	err := repo.conn.QueryRow(context.Background(),
-		"insert into hydrations(username, drink, amount, modified) values($1, $2, $3, $4) returning id",
+func (repo *HydrationPgRepository) Add(hydration models.Hydration, actor models.Actor) (int64, error) {
+	ctx := repo.context()
+
+	tx, err := repo.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx,
+		"insert into hydrations(username, drink, amount, drank_at, updated_at) values($1, $2, $3, $4, $5) returning id",
 		hydration.Username,
 		hydration.Drink,
 		hydration.Amount,
-		hydration.Modified,
-	).Scan(&hydrationID)
+		hydration.DrankAt,
+		hydration.UpdatedAt,
+	).Scan(&hydration.ID)
+	if err != nil {
+		return 0, err
+	}
 
-	return hydrationID, err
+	err = addRevision(ctx, tx, models.OperationAdd, hydration)
+	if err != nil {
+		return 0, err
+	}
+
+	err = addAuditLog(ctx, tx, actor, models.OperationAdd, hydration.ID, nil, &hydration)
+	if err != nil {
+		return 0, err
+	}
+
+	return hydration.ID, tx.Commit(ctx)
+}
+
+// AddBatch inserts hydration data in one transaction and returns number of inserted records.
+// Each record is saved in audit logs, but revisions are not saved, so imported records are not reverted by undo.
+func (repo *HydrationPgRepository) AddBatch(hydrationList []models.Hydration, actor models.Actor) (int64, error) {
+	ctx := repo.context()
+
+	tx, err := repo.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, hydration := range hydrationList {
+		err = tx.QueryRow(ctx,
+			"insert into hydrations(username, drink, amount, drank_at, updated_at) values($1, $2, $3, $4, $5) returning id",
+			hydration.Username,
+			hydration.Drink,
+			hydration.Amount,
+			hydration.DrankAt,
+			hydration.UpdatedAt,
+		).Scan(&hydration.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		err = addAuditLog(ctx, tx, actor, models.OperationAdd, hydration.ID, nil, &hydration)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(hydrationList)), tx.Commit(ctx)
 }
 
 // FetchOne fetches one hydration data.
 func (repo *HydrationPgRepository) FetchOne(hydrationID int64) (models.Hydration, error) {
+	return fetchOne(repo.context(), repo.conn, hydrationID, false)
+}
+
+// fetchOne fetches one hydration data. Deleted hydration is also returned if includeDeleted is true.
+func fetchOne(ctx context.Context, q queryRower, hydrationID int64, includeDeleted bool) (models.Hydration, error) {
 	var hydration models.Hydration
 	var userName string
 	var drink string
 	var amount int64
-	var modified time.Time
-	err := repo.conn.QueryRow(context.Background(), "select username, drink, amount, modified from hydrations where id = $1",
+	var drankAt time.Time
+	var updatedAt time.Time
+	var channel string
+	var messageTS string
+	err := q.QueryRow(ctx, "select username, drink, amount, drank_at, updated_at, channel, message_ts from hydrations where id = $1 and ($2 or deleted_at is null)",
 		hydrationID,
+		includeDeleted,
 	).Scan(
 		&userName,
 		&drink,
 		&amount,
-		&modified,
+		&drankAt,
+		&updatedAt,
+		&channel,
+		&messageTS,
 	)
 
 	if err != nil {
@@ -64,11 +187,14 @@ func (repo *HydrationPgRepository) FetchOne(hydrationID int64) (models.Hydration
 	}
 
 	hydration = models.Hydration{
-		ID:       hydrationID,
-		Username: userName,
-		Drink:    drink,
-		Amount:   amount,
-		Modified: modified,
+		ID:        hydrationID,
+		Username:  userName,
+		Drink:     drink,
+		Amount:    amount,
+		DrankAt:   drankAt,
+		UpdatedAt: updatedAt,
+		Channel:   channel,
+		MessageTS: messageTS,
 	}
 
 	return hydration, nil
@@ -77,16 +203,24 @@ func (repo *HydrationPgRepository) FetchOne(hydrationID int64) (models.Hydration
 // FetchDailyAmount gets summary of today's total drink amount.
 func (repo *HydrationPgRepository) FetchDailyAmount(userName string) (int64, error) {
 	var totalAmount int64
-	err := repo.conn.QueryRow(context.Background(), "select sum(amount) from hydrations where username = $1 and modified::date = now()::date", userName).Scan(&totalAmount)
+	err := repo.conn.QueryRow(repo.context(), "select coalesce(sum(amount), 0) from hydrations where username = $1 and drank_at::date = now()::date and deleted_at is null", userName).Scan(&totalAmount)
 
 	return totalAmount, err
 }
 
-// FetchWeeklyUsers returns user list with hydration record during this week.
+// FetchDailyStats returns numbers of records and users logged today.
+func (repo *HydrationPgRepository) FetchDailyStats() (models.DailyStats, error) {
+	var stats models.DailyStats
+	err := repo.conn.QueryRow(repo.context(), "select count(*), count(distinct username) from hydrations where drank_at::date = now()::date and deleted_at is null").Scan(&stats.Records, &stats.Users)
+
+	return stats, err
+}
+
+// FetchWeeklyUsers returns user list with hydration record during this week. Anonymized records are skipped.
 func (repo *HydrationPgRepository) FetchWeeklyUsers() ([]string, error) {
 	var userList []string
 
-	rows, err := repo.conn.Query(context.Background(), "select username from hydrations where modified >= now()::date - interval '7 days' group by username")
+	rows, err := repo.conn.Query(repo.context(), "select username from hydrations where drank_at >= now()::date - interval '7 days' and deleted_at is null and username <> '' group by username")
 	if err != nil {
 		return userList, err
 	}
@@ -109,16 +243,17 @@ func (repo *HydrationPgRepository) FetchWeeklySummary(userName string) ([]models
 
 	sql := []string{
 		"select ",
-		"extract(day from modified)::text as day ",
+		"extract(day from drank_at)::text as day ",
 		",sum(amount) as total_amount ",
 		"from hydrations ",
 		"where username = $1 ",
-		"and modified >= now()::date - interval '7 days' ",
-		"group by extract(day from modified) ",
-		"order by extract(day from modified)",
+		"and drank_at >= now()::date - interval '7 days' ",
+		"and deleted_at is null ",
+		"group by extract(day from drank_at) ",
+		"order by extract(day from drank_at)",
 	}
 
-	rows, err := repo.conn.Query(context.Background(), strings.Join(sql, " "), userName)
+	rows, err := repo.conn.Query(repo.context(), strings.Join(sql, " "), userName)
 	if err != nil {
 		return resultList, err
 	}
@@ -139,23 +274,979 @@ func (repo *HydrationPgRepository) FetchWeeklySummary(userName string) ([]models
 	return resultList, nil
 }
 
+// FetchDailySummaries returns daily total amounts of last days including days without records.
+func (repo *HydrationPgRepository) FetchDailySummaries(userName string, days int) ([]models.DailyHydrationSummary, error) {
+	var resultList []models.DailyHydrationSummary
+
+	sql := []string{
+		"select ",
+		"to_char(d.day, 'MM/DD') as day ",
+		",coalesce(sum(h.amount), 0) as total_amount ",
+		"from generate_series(now()::date - ($2::int - 1), now()::date, interval '1 day') as d(day) ",
+		"left join hydrations h on h.username = $1 and h.drank_at::date = d.day::date and h.deleted_at is null ",
+		"group by d.day ",
+		"order by d.day",
+	}
+
+	rows, err := repo.conn.Query(repo.context(), strings.Join(sql, " "), userName, days)
+	if err != nil {
+		return resultList, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day string
+		var totalAmount int64
+		err := rows.Scan(&day, &totalAmount)
+		if err != nil {
+			return resultList, err
+		}
+		resultList = append(resultList, models.DailyHydrationSummary{
+			Day:         day,
+			TotalAmount: totalAmount,
+		})
+	}
+
+	return resultList, rows.Err()
+}
+
+// FetchRecent returns latest hydration data.
+func (repo *HydrationPgRepository) FetchRecent(userName string, limit int) ([]models.Hydration, error) {
+	var hydrationList []models.Hydration
+
+	rows, err := repo.conn.Query(repo.context(), "select id, username, drink, amount, drank_at, updated_at, channel, message_ts from hydrations where username = $1 and deleted_at is null order by drank_at desc, id desc limit $2", userName, limit)
+	if err != nil {
+		return hydrationList, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hydration models.Hydration
+		err := rows.Scan(
+			&hydration.ID,
+			&hydration.Username,
+			&hydration.Drink,
+			&hydration.Amount,
+			&hydration.DrankAt,
+			&hydration.UpdatedAt,
+			&hydration.Channel,
+			&hydration.MessageTS,
+		)
+		if err != nil {
+			return hydrationList, err
+		}
+		hydrationList = append(hydrationList, hydration)
+	}
+
+	return hydrationList, rows.Err()
+}
+
+// FetchFrequentDrinks returns most frequent drink and amount pairs of user.
+func (repo *HydrationPgRepository) FetchFrequentDrinks(userName string, limit int) ([]models.FavoriteDrink, error) {
+	var favoriteList []models.FavoriteDrink
+
+	sql := []string{
+		"select ",
+		"drink ",
+		",amount ",
+		",count(*) as cnt ",
+		"from hydrations ",
+		"where username = $1 ",
+		"and deleted_at is null ",
+		"group by drink, amount ",
+		"order by cnt desc, max(drank_at) desc ",
+		"limit $2",
+	}
+
+	rows, err := repo.conn.Query(repo.context(), strings.Join(sql, " "), userName, limit)
+	if err != nil {
+		return favoriteList, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var favorite models.FavoriteDrink
+		err := rows.Scan(&favorite.Drink, &favorite.Amount, &favorite.Count)
+		if err != nil {
+			return favoriteList, err
+		}
+		favoriteList = append(favoriteList, favorite)
+	}
+
+	return favoriteList, rows.Err()
+}
+
+// FetchPinnedDrinks returns drinks pinned by user.
+func (repo *HydrationPgRepository) FetchPinnedDrinks(userName string) ([]models.FavoriteDrink, error) {
+	var favoriteList []models.FavoriteDrink
+
+	rows, err := repo.conn.Query(repo.context(), "select drink, amount from favorite_drinks where username = $1 order by id", userName)
+	if err != nil {
+		return favoriteList, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		favorite := models.FavoriteDrink{
+			Pinned: true,
+		}
+		err := rows.Scan(&favorite.Drink, &favorite.Amount)
+		if err != nil {
+			return favoriteList, err
+		}
+		favoriteList = append(favoriteList, favorite)
+	}
+
+	return favoriteList, rows.Err()
+}
+
+// PinDrink pins drink to favorites of user.
+func (repo *HydrationPgRepository) PinDrink(userName string, favorite models.FavoriteDrink) error {
+	_, err := repo.conn.Exec(repo.context(), "insert into favorite_drinks(username, drink, amount) values($1, $2, $3) on conflict (username, drink, amount) do nothing",
+		userName,
+		favorite.Drink,
+		favorite.Amount,
+	)
+	return err
+}
+
+// UnpinDrink removes drink from favorites of user.
+func (repo *HydrationPgRepository) UnpinDrink(userName string, favorite models.FavoriteDrink) error {
+	_, err := repo.conn.Exec(repo.context(), "delete from favorite_drinks where username = $1 and drink = $2 and amount = $3",
+		userName,
+		favorite.Drink,
+		favorite.Amount,
+	)
+	return err
+}
+
+// FetchUserSettings returns user settings. Zero values are returned if user has no settings.
+func (repo *HydrationPgRepository) FetchUserSettings(userName string) (models.UserSettings, error) {
+	settings := models.UserSettings{
+		Username: userName,
+	}
+
+	err := repo.conn.QueryRow(repo.context(), "select user_id, daily_goal, post_mode, post_channel, locale from user_settings where username = $1", userName).Scan(
+		&settings.UserID,
+		&settings.DailyGoal,
+		&settings.PostMode,
+		&settings.PostChannel,
+		&settings.Locale,
+	)
+	if err == pgx.ErrNoRows {
+		return settings, nil
+	}
+
+	return settings, err
+}
+
+// SaveUserSettings inserts or updates user settings.
+func (repo *HydrationPgRepository) SaveUserSettings(settings models.UserSettings) error {
+	sql := []string{
+		"insert into user_settings(username, user_id, daily_goal, post_mode, post_channel, locale) values($1, $2, $3, $4, $5, $6) ",
+		"on conflict (username) do update set ",
+		"user_id = excluded.user_id ",
+		",daily_goal = excluded.daily_goal ",
+		",post_mode = excluded.post_mode ",
+		",post_channel = excluded.post_channel ",
+		",locale = excluded.locale",
+	}
+
+	_, err := repo.conn.Exec(repo.context(), strings.Join(sql, " "),
+		settings.Username,
+		settings.UserID,
+		settings.DailyGoal,
+		settings.PostMode,
+		settings.PostChannel,
+		settings.Locale,
+	)
+	return err
+}
+
 // Update updates hydration data.
-func (repo *HydrationPgRepository) Update(hydration models.Hydration) error {
-	_, err := repo.conn.Exec(context.Background(), "update hydrations set drink = $1, amount = $2, modified = $3 where id = $4 and username = $5",
+func (repo *HydrationPgRepository) Update(hydration models.Hydration, actor models.Actor) error {
+	ctx := repo.context()
+
+	tx, err := repo.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	exHydration, err := fetchOne(ctx, tx, hydration.ID, false)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, "update hydrations set drink = $1, amount = $2, drank_at = $3, updated_at = $4 where id = $5 and username = $6 and deleted_at is null",
 		hydration.Drink,
 		hydration.Amount,
-		hydration.Modified,
+		hydration.DrankAt,
+		hydration.UpdatedAt,
 		hydration.ID,
 		hydration.Username,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	err = addRevision(ctx, tx, models.OperationUpdate, exHydration)
+	if err != nil {
+		return err
+	}
+
+	newHydration, err := fetchOne(ctx, tx, hydration.ID, false)
+	if err != nil {
+		return err
+	}
+
+	err = addAuditLog(ctx, tx, actor, models.OperationUpdate, hydration.ID, &exHydration, &newHydration)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
 }
 
 // Delete deletes hydration data.
-func (repo *HydrationPgRepository) Delete(hydration models.Hydration) error {
-	_, err := repo.conn.Exec(context.Background(), "delete from hydrations where id = $1 and username = $2",
+// Deleted hydration is kept until purged so that deletion can be undone.
+func (repo *HydrationPgRepository) Delete(hydration models.Hydration, actor models.Actor) error {
+	ctx := repo.context()
+
+	tx, err := repo.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	exHydration, err := fetchOne(ctx, tx, hydration.ID, false)
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, "update hydrations set deleted_at = now() where id = $1 and username = $2 and deleted_at is null",
 		hydration.ID,
 		hydration.Username,
 	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	err = addRevision(ctx, tx, models.OperationDelete, exHydration)
+	if err != nil {
+		return err
+	}
+
+	err = addAuditLog(ctx, tx, actor, models.OperationDelete, hydration.ID, &exHydration, nil)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SetMessage saves location of result message of hydration.
+func (repo *HydrationPgRepository) SetMessage(hydrationID int64, channel string, messageTS string) error {
+	_, err := repo.conn.Exec(repo.context(), "update hydrations set channel = $1, message_ts = $2 where id = $3",
+		channel,
+		messageTS,
+		hydrationID,
+	)
 	return err
 }
+
+// FetchRevision returns one revision.
+func (repo *HydrationPgRepository) FetchRevision(revisionID int64) (models.HydrationRevision, error) {
+	return fetchRevision(repo.context(), repo.conn, "where id = $1", revisionID)
+}
+
+// FetchLastRevision returns latest revision of user which is not undone yet.
+// Zero values are returned if there is no such revision.
+func (repo *HydrationPgRepository) FetchLastRevision(userName string) (models.HydrationRevision, error) {
+	revision, err := fetchRevision(repo.context(), repo.conn, "where username = $1 and undone_at is null order by id desc limit 1", userName)
+	if err == pgx.ErrNoRows {
+		return models.HydrationRevision{}, nil
+	}
+
+	return revision, err
+}
+
+// FetchLastHydrationRevision returns latest revision of hydration which is not undone yet.
+// Zero values are returned if there is no such revision.
+func (repo *HydrationPgRepository) FetchLastHydrationRevision(hydrationID int64) (models.HydrationRevision, error) {
+	revision, err := fetchRevision(repo.context(), repo.conn, "where hydration_id = $1 and undone_at is null order by id desc limit 1", hydrationID)
+	if err == pgx.ErrNoRows {
+		return models.HydrationRevision{}, nil
+	}
+
+	return revision, err
+}
+
+// Undo reverts operation of revision and returns reverted hydration.
+func (repo *HydrationPgRepository) Undo(revision models.HydrationRevision, actor models.Actor) (models.Hydration, error) {
+	var hydration models.Hydration
+	ctx := repo.context()
+
+	tx, err := repo.conn.Begin(ctx)
+	if err != nil {
+		return hydration, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "update hydration_revisions set undone_at = now() where id = $1 and undone_at is null", revision.ID)
+	if err != nil {
+		return hydration, err
+	}
+	if tag.RowsAffected() == 0 {
+		return hydration, pgx.ErrNoRows
+	}
+
+	var before *models.Hydration
+	exHydration, err := fetchOne(ctx, tx, revision.HydrationID, revision.Operation == models.OperationDelete)
+	if err != nil {
+		return hydration, err
+	}
+	if revision.Operation != models.OperationDelete {
+		before = &exHydration
+	}
+
+	switch revision.Operation {
+	case models.OperationAdd:
+		_, err = tx.Exec(ctx, "update hydrations set deleted_at = now() where id = $1", revision.HydrationID)
+	case models.OperationUpdate:
+		_, err = tx.Exec(ctx, "update hydrations set drink = $1, amount = $2, drank_at = $3, updated_at = now() where id = $4",
+			revision.Drink,
+			revision.Amount,
+			revision.DrankAt,
+			revision.HydrationID,
+		)
+	case models.OperationDelete:
+		_, err = tx.Exec(ctx, "update hydrations set deleted_at = null where id = $1", revision.HydrationID)
+	}
+	if err != nil {
+		return hydration, err
+	}
+
+	hydration, err = fetchOne(ctx, tx, revision.HydrationID, true)
+	if err != nil {
+		return hydration, err
+	}
+
+	var after *models.Hydration
+	if revision.Operation != models.OperationAdd {
+		after = &hydration
+	}
+
+	err = addAuditLog(ctx, tx, actor, "undo_"+revision.Operation, revision.HydrationID, before, after)
+	if err != nil {
+		return hydration, err
+	}
+
+	return hydration, tx.Commit(ctx)
+}
+
+// hydrationFilterSQL is condition of hydrations matched by filter given with hydrationFilterArgs as $1 to $4.
+const hydrationFilterSQL = "deleted_at is null " +
+	"and ($1 = '' or username = $1) " +
+	"and ($2 = '' or drink = $2) " +
+	"and ($3::timestamp is null or drank_at >= $3) " +
+	"and ($4::timestamp is null or drank_at < $4) "
+
+// hydrationFilterArgs returns arguments of hydrationFilterSQL followed by limit as $5, which is null if not set.
+func hydrationFilterArgs(filter models.HydrationFilter) []interface{} {
+	var from, to, limit interface{}
+	if !filter.From.IsZero() {
+		from = filter.From
+	}
+	if !filter.To.IsZero() {
+		to = filter.To
+	}
+	if filter.Limit > 0 {
+		limit = filter.Limit
+	}
+
+	return []interface{}{filter.Username, filter.Drink, from, to, limit}
+}
+
+// FetchHydrations returns hydration data matched by filter in order of drank time.
+func (repo *HydrationPgRepository) FetchHydrations(filter models.HydrationFilter) ([]models.Hydration, error) {
+	return fetchHydrations(repo.context(), repo.conn, filter, false)
+}
+
+// fetchHydrations returns hydration data matched by filter. Rows are locked until end of transaction if lock is true.
+func fetchHydrations(ctx context.Context, q queryer, filter models.HydrationFilter, lock bool) ([]models.Hydration, error) {
+	var hydrationList []models.Hydration
+
+	sql := "select id, username, drink, amount, drank_at, updated_at, channel, message_ts from hydrations where " + hydrationFilterSQL + "order by drank_at, id limit $5"
+	if lock {
+		sql += " for update"
+	}
+
+	rows, err := q.Query(ctx, sql, hydrationFilterArgs(filter)...)
+	if err != nil {
+		return hydrationList, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hydration models.Hydration
+		err := rows.Scan(
+			&hydration.ID,
+			&hydration.Username,
+			&hydration.Drink,
+			&hydration.Amount,
+			&hydration.DrankAt,
+			&hydration.UpdatedAt,
+			&hydration.Channel,
+			&hydration.MessageTS,
+		)
+		if err != nil {
+			return hydrationList, err
+		}
+		hydrationList = append(hydrationList, hydration)
+	}
+
+	return hydrationList, rows.Err()
+}
+
+// EachHydration calls fn with each hydration data of user in order of drank time without loading all of them.
+// Iteration stops at first error of fn, and the error is returned.
+func (repo *HydrationPgRepository) EachHydration(userName string, fn func(hydration models.Hydration) error) error {
+	rows, err := repo.conn.Query(repo.context(), "select id, username, drink, amount, drank_at, updated_at, channel, message_ts from hydrations where username = $1 and deleted_at is null order by drank_at, id", userName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var hydration models.Hydration
+		err := rows.Scan(
+			&hydration.ID,
+			&hydration.Username,
+			&hydration.Drink,
+			&hydration.Amount,
+			&hydration.DrankAt,
+			&hydration.UpdatedAt,
+			&hydration.Channel,
+			&hydration.MessageTS,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(hydration); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// FetchUserStats returns numbers and amounts of records matched by filter for each user.
+// Limit of filter limits number of users.
+func (repo *HydrationPgRepository) FetchUserStats(filter models.HydrationFilter) ([]models.UserStats, error) {
+	var statsList []models.UserStats
+
+	sql := []string{
+		"select ",
+		"username, count(*), sum(amount), count(distinct drank_at::date), min(drank_at), max(drank_at) ",
+		"from hydrations ",
+		"where " + hydrationFilterSQL,
+		"group by username ",
+		"order by username ",
+		"limit $5",
+	}
+
+	rows, err := repo.conn.Query(repo.context(), strings.Join(sql, " "), hydrationFilterArgs(filter)...)
+	if err != nil {
+		return statsList, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stats models.UserStats
+		err := rows.Scan(
+			&stats.Username,
+			&stats.Records,
+			&stats.TotalAmount,
+			&stats.Days,
+			&stats.FirstDrankAt,
+			&stats.LastDrankAt,
+		)
+		if err != nil {
+			return statsList, err
+		}
+		statsList = append(statsList, stats)
+	}
+
+	return statsList, rows.Err()
+}
+
+// MergeUsers moves hydration data including deleted records, revisions, favorites, settings and audit logs of user
+// to another user and returns number of moved records which are not deleted.
+// Settings are moved only if another user has none. Each moved record is saved in audit logs.
+func (repo *HydrationPgRepository) MergeUsers(fromUserName string, toUserName string, actor models.Actor) (int64, error) {
+	if fromUserName == toUserName {
+		return 0, errors.New("Users to merge must be different")
+	}
+
+	ctx := repo.context()
+
+	tx, err := repo.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// audit logs are moved before records so that audit logs of this merge are saved after them.
+	for _, sql := range mergeUsersSQL {
+		if _, err := tx.Exec(ctx, sql, fromUserName, toUserName); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, table := range []string{"favorite_drinks", "user_settings"} {
+		if _, err := tx.Exec(ctx, "delete from "+table+" where username = $1", fromUserName); err != nil {
+			return 0, err
+		}
+	}
+
+	moved, err := updateHydrations(ctx, tx, models.HydrationFilter{Username: fromUserName}, actor, func(hydration *models.Hydration) {
+		hydration.Username = toUserName
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return moved, tx.Commit(ctx)
+}
+
+// mergeUsersSQL moves data of user $1 to user $2 except records which are not deleted.
+// Every table keyed by user name must be listed so that merged user has no data left.
+// Favorites and settings which are not moved because of conflicts are deleted after them.
+var mergeUsersSQL = []string{
+	"update audit_logs set actor = case when actor = username then $2 else actor end, username = $2 where username = $1",
+	"update hydrations set username = $2 where username = $1 and deleted_at is not null",
+	"update hydration_revisions set username = $2 where username = $1",
+	"insert into favorite_drinks(username, drink, amount) select $2, drink, amount from favorite_drinks where username = $1 on conflict do nothing",
+	"update user_settings set username = $2 where username = $1 and not exists (select 1 from user_settings where username = $2)",
+}
+
+// RenameDrink renames drink of records matched by filter and returns their number. Drink of filter is required.
+// Favorites are also renamed if filter has no date range. Each renamed record is saved in audit logs.
+func (repo *HydrationPgRepository) RenameDrink(filter models.HydrationFilter, drink string, actor models.Actor) (int64, error) {
+	if len(filter.Drink) == 0 {
+		return 0, errors.New("Drink to rename is required")
+	}
+	if filter.Drink == drink {
+		return 0, errors.New("New name of drink must be different")
+	}
+
+	ctx := repo.context()
+
+	tx, err := repo.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	renamed, err := updateHydrations(ctx, tx, filter, actor, func(hydration *models.Hydration) {
+		hydration.Drink = drink
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if filter.From.IsZero() && filter.To.IsZero() {
+		_, err = tx.Exec(ctx, "insert into favorite_drinks(username, drink, amount) select username, $2, amount from favorite_drinks where drink = $1 and ($3 = '' or username = $3) on conflict do nothing",
+			filter.Drink,
+			drink,
+			filter.Username,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(ctx, "delete from favorite_drinks where drink = $1 and ($2 = '' or username = $2)", filter.Drink, filter.Username)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return renamed, tx.Commit(ctx)
+}
+
+// updateHydrations changes records matched by filter with change in transaction, saves each change in audit logs
+// and returns number of changed records.
+func updateHydrations(ctx context.Context, tx pgx.Tx, filter models.HydrationFilter, actor models.Actor, change func(hydration *models.Hydration)) (int64, error) {
+	hydrationList, err := fetchHydrations(ctx, tx, filter, true)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	for _, exHydration := range hydrationList {
+		hydration := exHydration
+		change(&hydration)
+		hydration.UpdatedAt = now
+
+		_, err := tx.Exec(ctx, "update hydrations set username = $1, drink = $2, updated_at = $3 where id = $4",
+			hydration.Username,
+			hydration.Drink,
+			hydration.UpdatedAt,
+			hydration.ID,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		err = addAuditLog(ctx, tx, actor, models.OperationUpdate, hydration.ID, &exHydration, &hydration)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(hydrationList)), nil
+}
+
+// DeleteUserData deletes hydration data including deleted records, revisions, favorites and settings of user,
+// removes user from audit logs, and returns number of deleted records.
+// Audit log of the deletion is saved as AnonymizedUser, and actor is also AnonymizedUser when user deleted own data.
+func (repo *HydrationPgRepository) DeleteUserData(userName string, actor models.Actor) (int64, error) {
+	if len(userName) == 0 {
+		return 0, errors.New("User to delete is required")
+	}
+
+	ctx := repo.context()
+
+	tx, err := repo.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, "delete from hydrations where username = $1", userName)
+	if err != nil {
+		return 0, err
+	}
+	deleted := tag.RowsAffected()
+
+	for _, table := range []string{"hydration_revisions", "favorite_drinks", "user_settings"} {
+		if _, err := tx.Exec(ctx, "delete from "+table+" where username = $1", userName); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, anonymizeAuditLogsSQL+"username = $2", models.ActionPurge, userName)
+	if err != nil {
+		return 0, err
+	}
+
+	if actor.Username == userName {
+		actor.Username = models.AnonymizedUser
+	}
+	err = addPurgeAuditLog(ctx, tx, actor, models.AnonymizedUser, purgeSummary{Mode: purgeModeDelete, Records: deleted})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, tx.Commit(ctx)
+}
+
+// PurgeBefore deletes hydration data drunk before cutoff, or removes user and message from them if anonymize is true,
+// and returns number of purged records. Revisions of purged records are deleted and user is removed from their audit logs,
+// and one audit log is saved for each user whose records are purged. Records anonymized before are purged as AnonymizedUser.
+func (repo *HydrationPgRepository) PurgeBefore(cutoff time.Time, anonymize bool, actor models.Actor) (int64, error) {
+	ctx := repo.context()
+
+	tx, err := repo.conn.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	sql := "delete from hydrations where drank_at < $1 returning id, username"
+	mode := purgeModeDelete
+	if anonymize {
+		sql = strings.Join([]string{
+			"with purged as (select id, username from hydrations where drank_at < $1 and username <> '' for update) ",
+			"update hydrations set username = '', channel = '', message_ts = '' ",
+			"from purged where hydrations.id = purged.id ",
+			"returning purged.id, purged.username",
+		}, "")
+		mode = purgeModeAnonymize
+	}
+
+	rows, err := tx.Query(ctx, sql, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	var hydrationIDs []int64
+	var userNames []string
+	purged := map[string]int64{}
+	for rows.Next() {
+		var hydrationID int64
+		var userName string
+		if err := rows.Scan(&hydrationID, &userName); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		hydrationIDs = append(hydrationIDs, hydrationID)
+		if len(userName) == 0 {
+			userName = models.AnonymizedUser
+		}
+		if purged[userName] == 0 {
+			userNames = append(userNames, userName)
+		}
+		purged[userName]++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(hydrationIDs) == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(ctx, "delete from hydration_revisions where hydration_id = any($1::bigint[])", hydrationIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, anonymizeAuditLogsSQL+"hydration_id = any($2::bigint[])", models.ActionPurge, hydrationIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, userName := range userNames {
+		err = addPurgeAuditLog(ctx, tx, actor, userName, purgeSummary{Mode: mode, Records: purged[userName], DrankBefore: &cutoff})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(hydrationIDs)), tx.Commit(ctx)
+}
+
+// anonymizeAuditLogsSQL removes user from audit logs matched by condition appended to it, except purge audit logs.
+// Audit logs are append-only, so they are kept without user instead of being deleted.
+// Actor is removed too when user changed own record. $1 is ActionPurge.
+const anonymizeAuditLogsSQL = "update audit_logs set actor = case when actor = username then '' else actor end, username = '', " +
+	"before = jsonb_set(before, '{username}', '\"\"'), after = jsonb_set(after, '{username}', '\"\"') " +
+	"where action <> $1 and "
+
+// Modes of purge saved in audit logs.
+const (
+	purgeModeDelete    = "delete"
+	purgeModeAnonymize = "anonymize"
+)
+
+// purgeSummary describes purged data saved as before of audit log.
+type purgeSummary struct {
+	Mode        string     `json:"mode"`
+	Records     int64      `json:"records"`
+	DrankBefore *time.Time `json:"drank_before,omitempty"`
+}
+
+// addPurgeAuditLog saves purge of data of user. Its hydration ID is 0 as it is not change of one record.
+func addPurgeAuditLog(ctx context.Context, tx pgx.Tx, actor models.Actor, userName string, summary purgeSummary) error {
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "insert into audit_logs(actor, action, hydration_id, username, before, after, source, created_at) values($1, $2, 0, $3, $4, null, $5, now())",
+		actor.Username,
+		models.ActionPurge,
+		userName,
+		string(summaryJSON),
+		actor.Source,
+	)
+	return err
+}
+
+// FetchAuditLogs returns latest audit logs matched by filter.
+func (repo *HydrationPgRepository) FetchAuditLogs(filter models.AuditLogFilter) ([]models.AuditLog, error) {
+	var auditLogList []models.AuditLog
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	sql := []string{
+		"select ",
+		"id, actor, action, hydration_id, username ",
+		",coalesce(before::text, ''), coalesce(after::text, '') ",
+		",source, created_at ",
+		"from audit_logs ",
+		"where ($1 = '' or username = $1 or actor = $1) ",
+		"and ($2 = 0 or hydration_id = $2) ",
+		"order by id desc ",
+		"limit $3",
+	}
+
+	rows, err := repo.conn.Query(repo.context(), strings.Join(sql, " "), filter.Username, filter.HydrationID, limit)
+	if err != nil {
+		return auditLogList, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var auditLog models.AuditLog
+		err := rows.Scan(
+			&auditLog.ID,
+			&auditLog.Actor,
+			&auditLog.Action,
+			&auditLog.HydrationID,
+			&auditLog.Username,
+			&auditLog.Before,
+			&auditLog.After,
+			&auditLog.Source,
+			&auditLog.CreatedAt,
+		)
+		if err != nil {
+			return auditLogList, err
+		}
+		auditLogList = append(auditLogList, auditLog)
+	}
+
+	return auditLogList, rows.Err()
+}
+
+// addAuditLog saves change of hydration. before or after is nil if record does not exist.
+func addAuditLog(ctx context.Context, tx pgx.Tx, actor models.Actor, action string, hydrationID int64, before *models.Hydration, after *models.Hydration) error {
+	var err error
+	var beforeJSON, afterJSON []byte
+	userName := actor.Username
+
+	if before != nil {
+		userName = before.Username
+		beforeJSON, err = json.Marshal(newAuditSnapshot(*before))
+		if err != nil {
+			return err
+		}
+	}
+
+	if after != nil {
+		userName = after.Username
+		afterJSON, err = json.Marshal(newAuditSnapshot(*after))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, "insert into audit_logs(actor, action, hydration_id, username, before, after, source, created_at) values($1, $2, $3, $4, $5, $6, $7, now())",
+		actor.Username,
+		action,
+		hydrationID,
+		userName,
+		nullableJSON(beforeJSON),
+		nullableJSON(afterJSON),
+		actor.Source,
+	)
+	return err
+}
+
+// auditSnapshot describes hydration values saved in audit logs.
+type auditSnapshot struct {
+	Username string    `json:"username"`
+	Drink    string    `json:"drink"`
+	Amount   int64     `json:"amount"`
+	DrankAt  time.Time `json:"drank_at"`
+}
+
+func newAuditSnapshot(hydration models.Hydration) auditSnapshot {
+	return auditSnapshot{
+		Username: hydration.Username,
+		Drink:    hydration.Drink,
+		Amount:   hydration.Amount,
+		DrankAt:  hydration.DrankAt,
+	}
+}
+
+// nullableJSON returns nil for empty JSON so that it is saved as null.
+func nullableJSON(value []byte) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	return string(value)
+}
+
+// addRevision saves hydration values before operation.
+func addRevision(ctx context.Context, tx pgx.Tx, operation string, hydration models.Hydration) error {
+	_, err := tx.Exec(ctx, "insert into hydration_revisions(hydration_id, username, operation, drink, amount, drank_at, created_at) values($1, $2, $3, $4, $5, $6, now())",
+		hydration.ID,
+		hydration.Username,
+		operation,
+		hydration.Drink,
+		hydration.Amount,
+		hydration.DrankAt,
+	)
+	return err
+}
+
+// fetchRevision returns revision matched by condition.
+// Age is computed in database, as created_at is wall clock of database and is read back as UTC.
+func fetchRevision(ctx context.Context, q queryRower, condition string, args ...interface{}) (models.HydrationRevision, error) {
+	var revision models.HydrationRevision
+	var ageSeconds float64
+	err := q.QueryRow(ctx, "select id, hydration_id, username, operation, drink, amount, drank_at, created_at, extract(epoch from now() - created_at)::float8, undone_at is not null from hydration_revisions "+condition,
+		args...,
+	).Scan(
+		&revision.ID,
+		&revision.HydrationID,
+		&revision.Username,
+		&revision.Operation,
+		&revision.Drink,
+		&revision.Amount,
+		&revision.DrankAt,
+		&revision.CreatedAt,
+		&ageSeconds,
+		&revision.Undone,
+	)
+	revision.Age = time.Duration(ageSeconds * float64(time.Second))
+
+	return revision, err
+}
+
+// ClaimDelivery records Slack delivery for ttl and returns false if it is already recorded and not expired.
+func (repo *HydrationPgRepository) ClaimDelivery(deliveryID string, ttl time.Duration) (bool, error) {
+	rows, err := repo.conn.Query(repo.context(),
+		"insert into slack_deliveries(delivery_id, expires_at) values($1, now() + $2 * interval '1 second') "+
+			"on conflict (delivery_id) do update set expires_at = excluded.expires_at where slack_deliveries.expires_at < now() "+
+			"returning delivery_id",
+		deliveryID,
+		ttl.Seconds(),
+	)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	claimed := rows.Next()
+	return claimed, rows.Err()
+}
+
+// ReleaseDelivery deletes record of Slack delivery so that its retry is handled.
+func (repo *HydrationPgRepository) ReleaseDelivery(deliveryID string) error {
+	_, err := repo.conn.Exec(repo.context(), "delete from slack_deliveries where delivery_id = $1", deliveryID)
+	return err
+}
+
+// DeleteExpiredDeliveries deletes expired records of Slack deliveries and returns their number.
+func (repo *HydrationPgRepository) DeleteExpiredDeliveries() (int64, error) {
+	tag, err := repo.conn.Exec(repo.context(), "delete from slack_deliveries where expires_at < now()")
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
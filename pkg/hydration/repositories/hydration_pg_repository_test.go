@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pirosuke/slack-bot-hydration/internal/database"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+)
+
+// createTablesPath is path of schema of database from this package.
+const createTablesPath = "../../../configs/sql/create_tables.sql"
+
+// testDatabaseURLEnv is environment variable with URL of PostgreSQL database for tests which need it.
+// Tables are created in temporary schema, which is dropped after test.
+const testDatabaseURLEnv = "HYDRATION_TEST_DATABASE_URL"
+
+func TestMergeUsersSQLCoversUserTables(t *testing.T) {
+	schema, err := os.ReadFile(createTablesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tablePattern := regexp.MustCompile(`(?s)create table (\w+)\((.*?)\n\)`)
+	for _, match := range tablePattern.FindAllStringSubmatch(string(schema), -1) {
+		table, columns := match[1], match[2]
+		if !regexp.MustCompile(`,?\s*username `).MatchString(columns) {
+			continue
+		}
+
+		covered := false
+		for _, sql := range mergeUsersSQL {
+			if strings.Contains(sql, " "+table+" ") {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			t.Errorf("%s has user name, but it is not moved by MergeUsers", table)
+		}
+	}
+}
+
+func TestMergeUsersMovesDeletedRecords(t *testing.T) {
+	repo := connectTestDatabase(t)
+	ctx := context.Background()
+
+	var liveID, deletedID int64
+	err := repo.conn.QueryRow(ctx, "insert into hydrations(username, drink, amount, drank_at, updated_at) values('alice.old', 'Water', 200, now(), now()) returning id").Scan(&liveID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.conn.QueryRow(ctx, "insert into hydrations(username, drink, amount, drank_at, updated_at, deleted_at) values('alice.old', 'Tea', 150, now(), now(), now()) returning id").Scan(&deletedID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sql := range []string{
+		"insert into hydration_revisions(hydration_id, username, operation, drink, amount, drank_at, created_at) values($1, 'alice.old', 'delete', 'Tea', 150, now(), now())",
+		"insert into audit_logs(actor, action, hydration_id, username, source, created_at) values('alice.old', 'delete', $1, 'alice.old', 'button', now())",
+	} {
+		if _, err := repo.conn.Exec(ctx, sql, deletedID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := repo.conn.Exec(ctx, "insert into favorite_drinks(username, drink, amount) values('alice.old', 'Tea', 150)"); err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := repo.MergeUsers("alice.old", "alice", models.Actor{Username: "admin", Source: "cli"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 1 {
+		t.Errorf("moved = %d, want 1 record which is not deleted", moved)
+	}
+
+	var userName string
+	if err := repo.conn.QueryRow(ctx, "select username from hydrations where id = $1", deletedID).Scan(&userName); err != nil {
+		t.Fatal(err)
+	}
+	if userName != "alice" {
+		t.Errorf("user of deleted record = %q, want %q", userName, "alice")
+	}
+
+	for _, table := range []string{"hydrations", "hydration_revisions", "favorite_drinks", "user_settings", "audit_logs"} {
+		var count int64
+		if err := repo.conn.QueryRow(ctx, "select count(*) from "+table+" where username = 'alice.old'").Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count > 0 {
+			t.Errorf("%s has %d rows of merged user", table, count)
+		}
+	}
+}
+
+func TestDeleteUserDataLogsAnonymizedUser(t *testing.T) {
+	repo := connectTestDatabase(t)
+	ctx := context.Background()
+
+	if _, err := repo.conn.Exec(ctx, "insert into hydrations(username, drink, amount, drank_at, updated_at) values('alice', 'Water', 200, now(), now())"); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := repo.DeleteUserData("alice", models.Actor{Username: "alice", Source: models.SourceButton})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Errorf("deleted = %d, want 1", deleted)
+	}
+
+	var count int64
+	if err := repo.conn.QueryRow(ctx, "select count(*) from audit_logs where username = 'alice' or actor = 'alice'").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count > 0 {
+		t.Errorf("%d audit logs have deleted user", count)
+	}
+
+	auditLogs, err := repo.FetchAuditLogs(models.AuditLogFilter{Username: models.AnonymizedUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(auditLogs) != 1 || auditLogs[0].Action != models.ActionPurge {
+		t.Errorf("audit logs of %s = %+v, want one purge", models.AnonymizedUser, auditLogs)
+	}
+}
+
+// connectTestDatabase returns repository connected to temporary schema of test database.
+// Test is skipped if test database is not set.
+func connectTestDatabase(t *testing.T) *HydrationPgRepository {
+	t.Helper()
+
+	dbURL := os.Getenv(testDatabaseURLEnv)
+	if len(dbURL) == 0 {
+		t.Skip(testDatabaseURLEnv, "is not set")
+	}
+
+	schemaSQL, err := os.ReadFile(createTablesPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close(ctx)
+
+	schema := fmt.Sprintf("hydration_test_%d", time.Now().UnixNano())
+	if _, err := conn.Exec(ctx, "create schema "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn, err := pgx.Connect(ctx, dbURL)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close(ctx)
+
+		if _, err := conn.Exec(ctx, "drop schema "+schema+" cascade"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	schemaURL, err := url.Parse(dbURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := schemaURL.Query()
+	query.Set("search_path", schema)
+	schemaURL.RawQuery = query.Encode()
+
+	repo := &HydrationPgRepository{}
+	if err := repo.Connect(database.DbConfig{URL: schemaURL.String()}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(repo.Close)
+
+	if _, err := repo.conn.Exec(ctx, string(schemaSQL)); err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
@@ -0,0 +1,353 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/interfaces"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/metrics"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// InstrumentedRepository records latency and span of each method of wrapped repository.
+// Connect and Close are not recorded.
+type InstrumentedRepository struct {
+	interfaces.HydrationRepository
+
+	ctx context.Context
+}
+
+// WithContext returns copy of repository whose methods are traced as child spans of span in ctx.
+// Queries of wrapped repository are canceled with ctx if it implements interfaces.ContextRepository.
+func (repo InstrumentedRepository) WithContext(ctx context.Context) InstrumentedRepository {
+	repo.ctx = ctx
+	if contextRepo, ok := repo.HydrationRepository.(interfaces.ContextRepository); ok {
+		repo.HydrationRepository = contextRepo.WithContext(ctx)
+	}
+	return repo
+}
+
+// observe starts span of method, and returns function which ends span and records latency.
+func (repo InstrumentedRepository) observe(method string) func(err error) {
+	ctx := repo.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	start := time.Now()
+	_, span := tracing.Start(ctx, "db "+method, attribute.String("db.system", "postgresql"), attribute.String("db.operation", method))
+
+	return func(err error) {
+		metrics.ObserveQuery(method, err, time.Since(start))
+		tracing.End(span, err)
+	}
+}
+
+// Ping calls Ping of wrapped repository.
+func (repo InstrumentedRepository) Ping() error {
+	end := repo.observe("Ping")
+	err := repo.HydrationRepository.Ping()
+	end(err)
+
+	return err
+}
+
+// Add calls Add of wrapped repository.
+func (repo InstrumentedRepository) Add(hydration models.Hydration, actor models.Actor) (int64, error) {
+	end := repo.observe("Add")
+	result, err := repo.HydrationRepository.Add(hydration, actor)
+	end(err)
+
+	return result, err
+}
+
+// AddBatch calls AddBatch of wrapped repository.
+func (repo InstrumentedRepository) AddBatch(hydrationList []models.Hydration, actor models.Actor) (int64, error) {
+	end := repo.observe("AddBatch")
+	result, err := repo.HydrationRepository.AddBatch(hydrationList, actor)
+	end(err)
+
+	return result, err
+}
+
+// FetchOne calls FetchOne of wrapped repository.
+func (repo InstrumentedRepository) FetchOne(hydrationID int64) (models.Hydration, error) {
+	end := repo.observe("FetchOne")
+	result, err := repo.HydrationRepository.FetchOne(hydrationID)
+	end(err)
+
+	return result, err
+}
+
+// FetchDailyAmount calls FetchDailyAmount of wrapped repository.
+func (repo InstrumentedRepository) FetchDailyAmount(userName string) (int64, error) {
+	end := repo.observe("FetchDailyAmount")
+	result, err := repo.HydrationRepository.FetchDailyAmount(userName)
+	end(err)
+
+	return result, err
+}
+
+// FetchDailyStats calls FetchDailyStats of wrapped repository.
+func (repo InstrumentedRepository) FetchDailyStats() (models.DailyStats, error) {
+	end := repo.observe("FetchDailyStats")
+	result, err := repo.HydrationRepository.FetchDailyStats()
+	end(err)
+
+	return result, err
+}
+
+// FetchWeeklyUsers calls FetchWeeklyUsers of wrapped repository.
+func (repo InstrumentedRepository) FetchWeeklyUsers() ([]string, error) {
+	end := repo.observe("FetchWeeklyUsers")
+	result, err := repo.HydrationRepository.FetchWeeklyUsers()
+	end(err)
+
+	return result, err
+}
+
+// FetchWeeklySummary calls FetchWeeklySummary of wrapped repository.
+func (repo InstrumentedRepository) FetchWeeklySummary(userName string) ([]models.DailyHydrationSummary, error) {
+	end := repo.observe("FetchWeeklySummary")
+	result, err := repo.HydrationRepository.FetchWeeklySummary(userName)
+	end(err)
+
+	return result, err
+}
+
+// FetchDailySummaries calls FetchDailySummaries of wrapped repository.
+func (repo InstrumentedRepository) FetchDailySummaries(userName string, days int) ([]models.DailyHydrationSummary, error) {
+	end := repo.observe("FetchDailySummaries")
+	result, err := repo.HydrationRepository.FetchDailySummaries(userName, days)
+	end(err)
+
+	return result, err
+}
+
+// FetchRecent calls FetchRecent of wrapped repository.
+func (repo InstrumentedRepository) FetchRecent(userName string, limit int) ([]models.Hydration, error) {
+	end := repo.observe("FetchRecent")
+	result, err := repo.HydrationRepository.FetchRecent(userName, limit)
+	end(err)
+
+	return result, err
+}
+
+// FetchFrequentDrinks calls FetchFrequentDrinks of wrapped repository.
+func (repo InstrumentedRepository) FetchFrequentDrinks(userName string, limit int) ([]models.FavoriteDrink, error) {
+	end := repo.observe("FetchFrequentDrinks")
+	result, err := repo.HydrationRepository.FetchFrequentDrinks(userName, limit)
+	end(err)
+
+	return result, err
+}
+
+// FetchPinnedDrinks calls FetchPinnedDrinks of wrapped repository.
+func (repo InstrumentedRepository) FetchPinnedDrinks(userName string) ([]models.FavoriteDrink, error) {
+	end := repo.observe("FetchPinnedDrinks")
+	result, err := repo.HydrationRepository.FetchPinnedDrinks(userName)
+	end(err)
+
+	return result, err
+}
+
+// PinDrink calls PinDrink of wrapped repository.
+func (repo InstrumentedRepository) PinDrink(userName string, favorite models.FavoriteDrink) error {
+	end := repo.observe("PinDrink")
+	err := repo.HydrationRepository.PinDrink(userName, favorite)
+	end(err)
+
+	return err
+}
+
+// UnpinDrink calls UnpinDrink of wrapped repository.
+func (repo InstrumentedRepository) UnpinDrink(userName string, favorite models.FavoriteDrink) error {
+	end := repo.observe("UnpinDrink")
+	err := repo.HydrationRepository.UnpinDrink(userName, favorite)
+	end(err)
+
+	return err
+}
+
+// FetchUserSettings calls FetchUserSettings of wrapped repository.
+func (repo InstrumentedRepository) FetchUserSettings(userName string) (models.UserSettings, error) {
+	end := repo.observe("FetchUserSettings")
+	result, err := repo.HydrationRepository.FetchUserSettings(userName)
+	end(err)
+
+	return result, err
+}
+
+// SaveUserSettings calls SaveUserSettings of wrapped repository.
+func (repo InstrumentedRepository) SaveUserSettings(settings models.UserSettings) error {
+	end := repo.observe("SaveUserSettings")
+	err := repo.HydrationRepository.SaveUserSettings(settings)
+	end(err)
+
+	return err
+}
+
+// Update calls Update of wrapped repository.
+func (repo InstrumentedRepository) Update(hydration models.Hydration, actor models.Actor) error {
+	end := repo.observe("Update")
+	err := repo.HydrationRepository.Update(hydration, actor)
+	end(err)
+
+	return err
+}
+
+// Delete calls Delete of wrapped repository.
+func (repo InstrumentedRepository) Delete(hydration models.Hydration, actor models.Actor) error {
+	end := repo.observe("Delete")
+	err := repo.HydrationRepository.Delete(hydration, actor)
+	end(err)
+
+	return err
+}
+
+// SetMessage calls SetMessage of wrapped repository.
+func (repo InstrumentedRepository) SetMessage(hydrationID int64, channel string, messageTS string) error {
+	end := repo.observe("SetMessage")
+	err := repo.HydrationRepository.SetMessage(hydrationID, channel, messageTS)
+	end(err)
+
+	return err
+}
+
+// FetchRevision calls FetchRevision of wrapped repository.
+func (repo InstrumentedRepository) FetchRevision(revisionID int64) (models.HydrationRevision, error) {
+	end := repo.observe("FetchRevision")
+	result, err := repo.HydrationRepository.FetchRevision(revisionID)
+	end(err)
+
+	return result, err
+}
+
+// FetchLastRevision calls FetchLastRevision of wrapped repository.
+func (repo InstrumentedRepository) FetchLastRevision(userName string) (models.HydrationRevision, error) {
+	end := repo.observe("FetchLastRevision")
+	result, err := repo.HydrationRepository.FetchLastRevision(userName)
+	end(err)
+
+	return result, err
+}
+
+// FetchLastHydrationRevision calls FetchLastHydrationRevision of wrapped repository.
+func (repo InstrumentedRepository) FetchLastHydrationRevision(hydrationID int64) (models.HydrationRevision, error) {
+	end := repo.observe("FetchLastHydrationRevision")
+	result, err := repo.HydrationRepository.FetchLastHydrationRevision(hydrationID)
+	end(err)
+
+	return result, err
+}
+
+// Undo calls Undo of wrapped repository.
+func (repo InstrumentedRepository) Undo(revision models.HydrationRevision, actor models.Actor) (models.Hydration, error) {
+	end := repo.observe("Undo")
+	result, err := repo.HydrationRepository.Undo(revision, actor)
+	end(err)
+
+	return result, err
+}
+
+// FetchHydrations calls FetchHydrations of wrapped repository.
+func (repo InstrumentedRepository) FetchHydrations(filter models.HydrationFilter) ([]models.Hydration, error) {
+	end := repo.observe("FetchHydrations")
+	result, err := repo.HydrationRepository.FetchHydrations(filter)
+	end(err)
+
+	return result, err
+}
+
+// EachHydration calls EachHydration of wrapped repository.
+// Recorded latency includes time spent in fn.
+func (repo InstrumentedRepository) EachHydration(userName string, fn func(hydration models.Hydration) error) error {
+	end := repo.observe("EachHydration")
+	err := repo.HydrationRepository.EachHydration(userName, fn)
+	end(err)
+
+	return err
+}
+
+// FetchUserStats calls FetchUserStats of wrapped repository.
+func (repo InstrumentedRepository) FetchUserStats(filter models.HydrationFilter) ([]models.UserStats, error) {
+	end := repo.observe("FetchUserStats")
+	result, err := repo.HydrationRepository.FetchUserStats(filter)
+	end(err)
+
+	return result, err
+}
+
+// MergeUsers calls MergeUsers of wrapped repository.
+func (repo InstrumentedRepository) MergeUsers(fromUserName string, toUserName string, actor models.Actor) (int64, error) {
+	end := repo.observe("MergeUsers")
+	result, err := repo.HydrationRepository.MergeUsers(fromUserName, toUserName, actor)
+	end(err)
+
+	return result, err
+}
+
+// RenameDrink calls RenameDrink of wrapped repository.
+func (repo InstrumentedRepository) RenameDrink(filter models.HydrationFilter, drink string, actor models.Actor) (int64, error) {
+	end := repo.observe("RenameDrink")
+	result, err := repo.HydrationRepository.RenameDrink(filter, drink, actor)
+	end(err)
+
+	return result, err
+}
+
+// DeleteUserData calls DeleteUserData of wrapped repository.
+func (repo InstrumentedRepository) DeleteUserData(userName string, actor models.Actor) (int64, error) {
+	end := repo.observe("DeleteUserData")
+	result, err := repo.HydrationRepository.DeleteUserData(userName, actor)
+	end(err)
+
+	return result, err
+}
+
+// PurgeBefore calls PurgeBefore of wrapped repository.
+func (repo InstrumentedRepository) PurgeBefore(cutoff time.Time, anonymize bool, actor models.Actor) (int64, error) {
+	end := repo.observe("PurgeBefore")
+	result, err := repo.HydrationRepository.PurgeBefore(cutoff, anonymize, actor)
+	end(err)
+
+	return result, err
+}
+
+// FetchAuditLogs calls FetchAuditLogs of wrapped repository.
+func (repo InstrumentedRepository) FetchAuditLogs(filter models.AuditLogFilter) ([]models.AuditLog, error) {
+	end := repo.observe("FetchAuditLogs")
+	result, err := repo.HydrationRepository.FetchAuditLogs(filter)
+	end(err)
+
+	return result, err
+}
+
+// ClaimDelivery calls ClaimDelivery of wrapped repository.
+func (repo InstrumentedRepository) ClaimDelivery(deliveryID string, ttl time.Duration) (bool, error) {
+	end := repo.observe("ClaimDelivery")
+	result, err := repo.HydrationRepository.ClaimDelivery(deliveryID, ttl)
+	end(err)
+
+	return result, err
+}
+
+// ReleaseDelivery calls ReleaseDelivery of wrapped repository.
+func (repo InstrumentedRepository) ReleaseDelivery(deliveryID string) error {
+	end := repo.observe("ReleaseDelivery")
+	err := repo.HydrationRepository.ReleaseDelivery(deliveryID)
+	end(err)
+
+	return err
+}
+
+// DeleteExpiredDeliveries calls DeleteExpiredDeliveries of wrapped repository.
+func (repo InstrumentedRepository) DeleteExpiredDeliveries() (int64, error) {
+	end := repo.observe("DeleteExpiredDeliveries")
+	result, err := repo.HydrationRepository.DeleteExpiredDeliveries()
+	end(err)
+
+	return result, err
+}
@@ -1,57 +1,89 @@
 package repositories
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"mime/multipart"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mattn/go-jsonpointer"
-	"github.com/pirosuke/slack-bot-hydration/internal/file"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/export"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/i18n"
 	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/routing"
 	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/slack"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/validation"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/views"
 )
 
 // SlackRepository controls posts to Slack.
+// Views are rendered in Locale.
+// Slack calls are logged with request ID of ctx.
 type SlackRepository struct {
-	Token        string
-	ViewsDirPath string
+	Token  string
+	Views  *views.Set
+	Locale string
+
+	ctx context.Context
 }
 
-// ShowAlert opens modal for alert.
-func (repo *SlackRepository) ShowAlert(triggerID string, title string, text string) ([]byte, error) {
+// ForLocale returns copy of repository which renders views in locale.
+func (repo *SlackRepository) ForLocale(locale string) *SlackRepository {
+	localized := *repo
+	localized.Locale = locale
+	return &localized
+}
+
+// WithContext returns copy of repository whose Slack calls carry request ID of ctx and are canceled with ctx.
+// Background jobs pass their own context, which outlives the request.
+func (repo *SlackRepository) WithContext(ctx context.Context) *SlackRepository {
+	withContext := *repo
+	withContext.ctx = ctx
+	return &withContext
+}
 
+// context returns context of Slack calls.
+func (repo *SlackRepository) context() context.Context {
+	if repo.ctx == nil {
+		return context.Background()
+	}
+	return repo.ctx
+}
+
+// RenderAlert returns alert view which replaces current modal.
+func (repo *SlackRepository) RenderAlert(title string, text string) (interface{}, error) {
 	viewParams := map[string]string{
 		"title": title,
 		"text":  text,
 	}
 
-	var resp []byte
+	return repo.renderView("alert_dialog.json", viewParams)
+}
 
-	viewPath := filepath.Join(repo.ViewsDirPath, "alert_dialog.json")
-	if !file.FileExists(viewPath) {
-		return resp, fmt.Errorf("View file does not exist: %s", viewPath)
+// ShowAlert opens modal for alert.
+func (repo *SlackRepository) ShowAlert(triggerID string, title string, text string) ([]byte, error) {
+
+	viewParams := map[string]string{
+		"title": title,
+		"text":  text,
 	}
 
-	return repo.openView(triggerID, viewPath, viewParams)
+	return repo.openView(triggerID, "alert_dialog.json", viewParams)
 }
 
 // PostHydrationAddResult posts hydration added result message.
-func (repo *SlackRepository) PostHydrationAddResult(userName string, channel string, hydration models.Hydration, dailyAmount int64) ([]byte, error) {
+// Time of drink is shown in loc.
+func (repo *SlackRepository) PostHydrationAddResult(userName string, channel string, hydration models.Hydration, dailyAmount int64, loc *time.Location) ([]byte, error) {
 	var err error
 	var resp []byte
-	var requestParams, view interface{}
-
-	viewPath := filepath.Join(repo.ViewsDirPath, "result_message.json")
-	if !file.FileExists(viewPath) {
-		return resp, fmt.Errorf("View file does not exist: %s", viewPath)
-	}
+	var requestParams interface{}
 
 	requestJSON := `{"channel": "", "blocks": []}`
 	err = json.Unmarshal([]byte(requestJSON), &requestParams)
@@ -59,22 +91,16 @@ func (repo *SlackRepository) PostHydrationAddResult(userName string, channel str
 		return resp, err
 	}
 
-	viewJSONTemplate, err := ioutil.ReadFile(viewPath)
-	if err != nil {
-		return resp, err
-	}
-
 	viewParams := map[string]string{
 		"hydrationID": strconv.FormatInt(hydration.ID, 10),
 		"userName":    hydration.Username,
 		"drink":       hydration.Drink,
 		"amount":      strconv.FormatInt(hydration.Amount, 10),
+		"drankAt":     formatDrankAt(hydration.DrankAt, loc),
 		"dailyAmount": strconv.FormatInt(dailyAmount, 10),
 	}
 
-	viewJSON := replaceViewTemplateParams(string(viewJSONTemplate), viewParams)
-
-	err = json.Unmarshal([]byte(viewJSON), &view)
+	view, err := repo.renderView("result_message.json", viewParams)
 	if err != nil {
 		return resp, err
 	}
@@ -94,24 +120,17 @@ func (repo *SlackRepository) PostHydrationAddResult(userName string, channel str
 		return resp, err
 	}
 
-	fmt.Println(string(requestParamsJSON))
-
-	resp, err = slack.PostJSON(repo.Token, "chat.postMessage", "application/json", string(requestParamsJSON))
-	fmt.Println(string(resp))
+	resp, err = slack.PostJSON(repo.context(), repo.Token, "chat.postMessage", "application/json", string(requestParamsJSON))
 
 	return resp, err
 }
 
 // PostHydrationUpdateResult posts hydration added result message.
-func (repo *SlackRepository) PostHydrationUpdateResult(userName string, channel string, ts string, hydration models.Hydration, dailyAmount int64) ([]byte, error) {
+// Time of drink is shown in loc.
+func (repo *SlackRepository) PostHydrationUpdateResult(userName string, channel string, ts string, hydration models.Hydration, dailyAmount int64, loc *time.Location) ([]byte, error) {
 	var err error
 	var resp []byte
-	var requestParams, view interface{}
-
-	viewPath := filepath.Join(repo.ViewsDirPath, "result_message.json")
-	if !file.FileExists(viewPath) {
-		return resp, fmt.Errorf("View file does not exist: %s", viewPath)
-	}
+	var requestParams interface{}
 
 	requestJSON := `{"channel": "", "ts": "", "blocks": []}`
 	err = json.Unmarshal([]byte(requestJSON), &requestParams)
@@ -119,22 +138,16 @@ func (repo *SlackRepository) PostHydrationUpdateResult(userName string, channel
 		return resp, err
 	}
 
-	viewJSONTemplate, err := ioutil.ReadFile(viewPath)
-	if err != nil {
-		return resp, err
-	}
-
 	viewParams := map[string]string{
 		"hydrationID": strconv.FormatInt(hydration.ID, 10),
 		"userName":    hydration.Username,
 		"drink":       hydration.Drink,
 		"amount":      strconv.FormatInt(hydration.Amount, 10),
+		"drankAt":     formatDrankAt(hydration.DrankAt, loc),
 		"dailyAmount": strconv.FormatInt(dailyAmount, 10),
 	}
 
-	viewJSON := replaceViewTemplateParams(string(viewJSONTemplate), viewParams)
-
-	err = json.Unmarshal([]byte(viewJSON), &view)
+	view, err := repo.renderView("result_message.json", viewParams)
 	if err != nil {
 		return resp, err
 	}
@@ -159,35 +172,61 @@ func (repo *SlackRepository) PostHydrationUpdateResult(userName string, channel
 		return resp, err
 	}
 
-	fmt.Println(string(requestParamsJSON))
-
-	resp, err = slack.PostJSON(repo.Token, "chat.update", "application/json", string(requestParamsJSON))
-	fmt.Println(string(resp))
+	resp, err = slack.PostJSON(repo.context(), repo.Token, "chat.update", "application/json", string(requestParamsJSON))
 
 	return resp, err
 }
 
-// OpenHydrationAddView opens modal for adding Hydration.
-func (repo *SlackRepository) OpenHydrationAddView(triggerID string) ([]byte, error) {
+// OpenHydrationAddView opens modal for adding Hydration, with current date and time in loc.
+func (repo *SlackRepository) OpenHydrationAddView(triggerID string, metadata string, loc *time.Location) ([]byte, error) {
+	var resp []byte
+	now := time.Now().In(loc)
 
 	viewParams := map[string]string{
 		"callbackID":    "hydration__record_form",
-		"metadata":      "",
+		"metadata":      metadata,
 		"initialDrink":  "",
+		"minAmount":     strconv.Itoa(validation.MinAmount),
+		"maxAmount":     strconv.Itoa(validation.MaxAmount),
 		"initialAmount": "100",
+		"initialDate":   now.Format(validation.DateFormat),
+		"initialTime":   now.Format(validation.TimeFormat),
 	}
 
-	return repo.openHydrationEditView(triggerID, viewParams)
+	view, err := repo.renderView("record_form.json", viewParams)
+	if err != nil {
+		return resp, err
+	}
+
+	channelBlock, err := repo.renderView("record_form_channel.json", map[string]string{})
+	if err != nil {
+		return resp, err
+	}
+
+	blocks, _ := jsonpointer.Get(view, "/blocks")
+	blockList, _ := blocks.([]interface{})
+
+	err = jsonpointer.Set(view, "/blocks", append(blockList, channelBlock))
+	if err != nil {
+		return resp, err
+	}
+
+	return repo.openRenderedView(triggerID, view)
 }
 
-// OpenHydrationUpdateView opens modal for updating Hydration.
-func (repo *SlackRepository) OpenHydrationUpdateView(triggerID string, channel string, ts string, hydration models.Hydration) ([]byte, error) {
+// OpenHydrationUpdateView opens modal for updating Hydration, with drinking date and time in loc.
+func (repo *SlackRepository) OpenHydrationUpdateView(triggerID string, metadata string, hydration models.Hydration, loc *time.Location) ([]byte, error) {
+	drankAt := export.InLocation(hydration.DrankAt, loc)
 
 	viewParams := map[string]string{
 		"callbackID":    "hydration__update_form",
-		"metadata":      channel + "-" + ts + "-" + strconv.FormatInt(hydration.ID, 10),
+		"metadata":      metadata,
 		"initialDrink":  hydration.Drink,
+		"minAmount":     strconv.Itoa(validation.MinAmount),
+		"maxAmount":     strconv.Itoa(validation.MaxAmount),
 		"initialAmount": strconv.FormatInt(hydration.Amount, 10),
+		"initialDate":   drankAt.Format(validation.DateFormat),
+		"initialTime":   drankAt.Format(validation.TimeFormat),
 	}
 
 	return repo.openHydrationEditView(triggerID, viewParams)
@@ -195,14 +234,7 @@ func (repo *SlackRepository) OpenHydrationUpdateView(triggerID string, channel s
 
 // openHydrationEditView opens modal for adding Hydration.
 func (repo *SlackRepository) openHydrationEditView(triggerID string, viewParams map[string]string) ([]byte, error) {
-	var resp []byte
-
-	viewPath := filepath.Join(repo.ViewsDirPath, "record_form.json")
-	if !file.FileExists(viewPath) {
-		return resp, fmt.Errorf("View file does not exist: %s", viewPath)
-	}
-
-	return repo.openView(triggerID, viewPath, viewParams)
+	return repo.openView(triggerID, "record_form.json", viewParams)
 }
 
 // DeleteMessage deletes message.
@@ -232,81 +264,664 @@ func (repo *SlackRepository) DeleteMessage(channel string, ts string) ([]byte, e
 		return resp, err
 	}
 
-	//fmt.Println(string(requestParamsJSON))
-	resp, err = slack.PostJSON(repo.Token, "chat.delete", "application/json", string(requestParamsJSON))
-	//fmt.Println(string(resp))
+	resp, err = slack.PostJSON(repo.context(), repo.Token, "chat.delete", "application/json", string(requestParamsJSON))
 
 	return resp, err
 
 }
 
 // UploadFile uploads file.
+// File is streamed to Slack while multipart body is written, so that it is not loaded into memory.
 func (repo *SlackRepository) UploadFile(channel string, fileName string, fileType string, filePath string, message string) ([]byte, error) {
+	fileReader, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer fileReader.Close()
+
+	bodyReader, bodyWriter := io.Pipe()
+	writer := multipart.NewWriter(bodyWriter)
+
+	go func() {
+		fields := [][2]string{
+			{"token", repo.Token},
+			{"channels", channel},
+			{"filename", fileName},
+			{"filetype", fileType},
+		}
+		if len(message) > 0 {
+			fields = append(fields, [2]string{"initial_comment", message})
+		}
+
+		for _, field := range fields {
+			if err := writer.WriteField(field[0], field[1]); err != nil {
+				bodyWriter.CloseWithError(err)
+				return
+			}
+		}
+
+		fw, err := writer.CreateFormFile("file", filepath.Base(fileReader.Name()))
+		if err == nil {
+			_, err = io.Copy(fw, fileReader)
+		}
+		if err == nil {
+			err = writer.Close()
+		}
+		bodyWriter.CloseWithError(err)
+	}()
+
+	resp, err := slack.PostReader(repo.context(), repo.Token, "files.upload", writer.FormDataContentType(), bodyReader)
+	// unblocks writer if request failed before whole body was read.
+	bodyReader.Close()
+
+	return resp, err
+}
+
+// PublishHome publishes App Home dashboard of user.
+// Times of recent drinks are shown in loc.
+func (repo *SlackRepository) PublishHome(userID string, dashboard models.HydrationDashboard, loc *time.Location) ([]byte, error) {
 	var err error
 	var resp []byte
+	var requestParams interface{}
 
-	fileReader, err := os.Open(filePath)
+	requestJSON := `{"user_id": "", "view": {}}`
+	err = json.Unmarshal([]byte(requestJSON), &requestParams)
 	if err != nil {
 		return resp, err
 	}
-	defer fileReader.Close()
 
-	var requestParams bytes.Buffer
-	var fw io.Writer
+	var amountList []int64
+	var maxAmount int64
+	for _, summary := range dashboard.DailySummaries {
+		amountList = append(amountList, summary.TotalAmount)
+		if summary.TotalAmount > maxAmount {
+			maxAmount = summary.TotalAmount
+		}
+	}
 
-	writer := multipart.NewWriter(&requestParams)
+	var firstDay, lastDay string
+	if len(dashboard.DailySummaries) > 0 {
+		firstDay = dashboard.DailySummaries[0].Day
+		lastDay = dashboard.DailySummaries[len(dashboard.DailySummaries)-1].Day
+	}
 
-	fw, _ = writer.CreateFormField("token")
-	io.Copy(fw, strings.NewReader(repo.Token))
+	var progressPercent int64
+	if dashboard.DailyGoal > 0 {
+		progressPercent = dashboard.DailyAmount * 100 / dashboard.DailyGoal
+	}
 
-	fw, _ = writer.CreateFormField("channels")
-	io.Copy(fw, strings.NewReader(channel))
+	viewParams := map[string]string{
+		"dailyAmount":     strconv.FormatInt(dashboard.DailyAmount, 10),
+		"dailyGoal":       strconv.FormatInt(dashboard.DailyGoal, 10),
+		"progressBar":     progressBar(progressPercent, 20),
+		"progressPercent": strconv.FormatInt(progressPercent, 10),
+		"sparkline":       sparkline(amountList),
+		"firstDay":        firstDay,
+		"lastDay":         lastDay,
+		"maxAmount":       strconv.FormatInt(maxAmount, 10),
+	}
 
-	fw, _ = writer.CreateFormField("filename")
-	io.Copy(fw, strings.NewReader(fileName))
+	view, err := repo.renderView("home_view.json", viewParams)
+	if err != nil {
+		return resp, err
+	}
 
-	fw, _ = writer.CreateFormField("filetype")
-	io.Copy(fw, strings.NewReader(fileType))
+	blocks, _ := jsonpointer.Get(view, "/blocks")
+	blockList, _ := blocks.([]interface{})
 
-	fw, _ = writer.CreateFormFile("file", fileReader.Name())
-	io.Copy(fw, fileReader)
+	if dashboard.UndoRevision.ID > 0 {
+		undoBlock, err := repo.renderView("home_undo.json", undoViewParams(repo.Locale, dashboard.UndoRevision))
+		if err != nil {
+			return resp, err
+		}
+		blockList = append(blockList, undoBlock)
+	}
+
+	if len(dashboard.FavoriteDrinks) > 0 {
+		favoriteBlocks, err := repo.renderView("favorites_block.json", map[string]string{})
+		if err != nil {
+			return resp, err
+		}
+
+		buttonList, err := repo.renderFavoriteButtons(dashboard.FavoriteDrinks)
+		if err != nil {
+			return resp, err
+		}
+
+		err = jsonpointer.Set(favoriteBlocks, "/2/elements", buttonList)
+		if err != nil {
+			return resp, err
+		}
+
+		favoriteBlockList, _ := favoriteBlocks.([]interface{})
+		blockList = append(blockList, favoriteBlockList...)
 
-	if len(message) > 0 {
-		fw, _ = writer.CreateFormField("initial_comment")
-		io.Copy(fw, strings.NewReader(message))
+		for _, favorite := range dashboard.FavoriteDrinks {
+			if !favorite.Pinned {
+				continue
+			}
+
+			pinnedBlock, err := repo.renderView("favorite_pinned.json", favoriteViewParams(favorite))
+			if err != nil {
+				return resp, err
+			}
+			blockList = append(blockList, pinnedBlock)
+		}
 	}
 
-	writer.Close()
+	headerBlocks, err := repo.renderView("home_records_header.json", map[string]string{})
+	if err != nil {
+		return resp, err
+	}
+	headerBlockList, _ := headerBlocks.([]interface{})
+	blockList = append(blockList, headerBlockList...)
 
-	//fmt.Println(string(requestParamsJSON))
-	resp, err = slack.PostBuffer(repo.Token, "files.upload", writer.FormDataContentType(), &requestParams)
-	//fmt.Println(string(resp))
+	for _, hydration := range dashboard.RecentHydrations {
+		recordParams := map[string]string{
+			"hydrationID": strconv.FormatInt(hydration.ID, 10),
+			"drink":       hydration.Drink,
+			"amount":      strconv.FormatInt(hydration.Amount, 10),
+			"drankAt":     formatDrankAt(hydration.DrankAt, loc),
+		}
 
-	return resp, err
+		recordBlocks, err := repo.renderView("home_record.json", recordParams)
+		if err != nil {
+			return resp, err
+		}
+		recordBlockList, _ := recordBlocks.([]interface{})
+		blockList = append(blockList, recordBlockList...)
+	}
+
+	if len(dashboard.RecentHydrations) == 0 {
+		emptyBlocks, err := repo.renderView("home_empty.json", map[string]string{})
+		if err != nil {
+			return resp, err
+		}
+		emptyBlockList, _ := emptyBlocks.([]interface{})
+		blockList = append(blockList, emptyBlockList...)
+	}
+
+	err = jsonpointer.Set(view, "/blocks", blockList)
+	if err != nil {
+		return resp, err
+	}
+
+	err = views.Validate(view)
+	if err != nil {
+		return resp, err
+	}
+
+	err = jsonpointer.Set(requestParams, "/view", view)
+	if err != nil {
+		return resp, err
+	}
+
+	err = jsonpointer.Set(requestParams, "/user_id", userID)
+	if err != nil {
+		return resp, err
+	}
+
+	requestParamsJSON, err := json.Marshal(requestParams)
+	if err != nil {
+		return resp, err
+	}
 
+	return slack.PostJSON(repo.context(), repo.Token, "views.publish", "application/json", string(requestParamsJSON))
 }
 
-// openView opens modal from template.
-func (repo *SlackRepository) openView(triggerID string, viewPath string, viewParams map[string]string) ([]byte, error) {
+// OpenFavoritesView opens modal with quick logging buttons of favorite drinks.
+func (repo *SlackRepository) OpenFavoritesView(triggerID string, favorites []models.FavoriteDrink) ([]byte, error) {
+	var resp []byte
+
+	view, err := repo.renderView("favorites_dialog.json", map[string]string{})
+	if err != nil {
+		return resp, err
+	}
+
+	buttonList, err := repo.renderFavoriteButtons(favorites)
+	if err != nil {
+		return resp, err
+	}
+
+	err = jsonpointer.Set(view, "/blocks/1/elements", buttonList)
+	if err != nil {
+		return resp, err
+	}
+
+	return repo.openRenderedView(triggerID, view)
+}
+
+// PostFavoriteButtons posts message with quick logging buttons of favorite drinks to channel.
+func (repo *SlackRepository) PostFavoriteButtons(channel string, favorites []models.FavoriteDrink) ([]byte, error) {
+	var resp []byte
+
+	view, err := repo.renderView("favorites_message.json", map[string]string{})
+	if err != nil {
+		return resp, err
+	}
+
+	buttonList, err := repo.renderFavoriteButtons(favorites)
+	if err != nil {
+		return resp, err
+	}
+
+	err = jsonpointer.Set(view, "/1/elements", buttonList)
+	if err != nil {
+		return resp, err
+	}
+
+	requestParamsJSON, err := json.Marshal(map[string]interface{}{
+		"channel": channel,
+		"text":    i18n.T(repo.Locale, "favorites.hint"),
+		"blocks":  view,
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	return slack.PostJSON(repo.context(), repo.Token, "chat.postMessage", "application/json", string(requestParamsJSON))
+}
+
+// PostUndoMessage posts message only visible to user with button which undoes operation of revision.
+func (repo *SlackRepository) PostUndoMessage(channel string, userID string, revision models.HydrationRevision) ([]byte, error) {
 	var err error
 	var resp []byte
-	var requestParams, view interface{}
+	var requestParams interface{}
 
-	requestJSON := `{"trigger_id": "", "view": {}}`
+	requestJSON := `{"channel": "", "user": "", "text": "", "blocks": []}`
 	err = json.Unmarshal([]byte(requestJSON), &requestParams)
 	if err != nil {
 		return resp, err
 	}
 
-	viewJSONTemplate, err := ioutil.ReadFile(viewPath)
+	viewParams := undoViewParams(repo.Locale, revision)
+
+	view, err := repo.renderView("undo_message.json", viewParams)
+	if err != nil {
+		return resp, err
+	}
+
+	err = jsonpointer.Set(requestParams, "/blocks", view)
+	if err != nil {
+		return resp, err
+	}
+
+	err = jsonpointer.Set(requestParams, "/text", slack.EscapeMrkdwn(viewParams["text"]))
+	if err != nil {
+		return resp, err
+	}
+
+	err = jsonpointer.Set(requestParams, "/channel", channel)
+	if err != nil {
+		return resp, err
+	}
+
+	err = jsonpointer.Set(requestParams, "/user", userID)
+	if err != nil {
+		return resp, err
+	}
+
+	requestParamsJSON, err := json.Marshal(requestParams)
 	if err != nil {
 		return resp, err
 	}
 
-	viewJSON := replaceViewTemplateParams(string(viewJSONTemplate), viewParams)
-	//fmt.Println(viewJSON)
+	return slack.PostJSON(repo.context(), repo.Token, "chat.postEphemeral", "application/json", string(requestParamsJSON))
+}
+
+// PostImportReport posts report of file to import with buttons which import it or cancel.
+// request is value of buttons which identifies file.
+func (repo *SlackRepository) PostImportReport(channel string, text string, request string) ([]byte, error) {
+	var resp []byte
+
+	view, err := repo.renderView("import_report.json", map[string]string{
+		"text":    text,
+		"request": request,
+	})
+	if err != nil {
+		return resp, err
+	}
 
-	err = json.Unmarshal([]byte(viewJSON), &view)
+	requestParamsJSON, err := json.Marshal(map[string]interface{}{
+		"channel": channel,
+		"text":    slack.EscapeMrkdwn(text),
+		"blocks":  view,
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	return slack.PostJSON(repo.context(), repo.Token, "chat.postMessage", "application/json", string(requestParamsJSON))
+}
+
+// RenderDataDeletion returns blocks of message with button which deletes data after confirmation.
+// Button has actionID and value, and confirmText is shown in confirmation dialog.
+// Value must not be empty, as Slack rejects buttons with empty value.
+func (repo *SlackRepository) RenderDataDeletion(text string, actionID string, value string, confirmText string) (interface{}, error) {
+	return repo.renderView("data_deletion.json", map[string]string{
+		"text":        text,
+		"actionID":    actionID,
+		"value":       value,
+		"confirmText": confirmText,
+	})
+}
+
+// PostText posts text message to channel. Text is escaped, so it is shown as is.
+func (repo *SlackRepository) PostText(channel string, text string) ([]byte, error) {
+	requestParamsJSON, err := json.Marshal(map[string]interface{}{
+		"channel": channel,
+		"text":    slack.EscapeMrkdwn(text),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return slack.PostJSON(repo.context(), repo.Token, "chat.postMessage", "application/json", string(requestParamsJSON))
+}
+
+// Respond posts text to response_url of interaction or slash command.
+// Original message is replaced if replaceOriginal is true.
+func (repo *SlackRepository) Respond(responseURL string, text string, replaceOriginal bool) ([]byte, error) {
+	requestParams := map[string]interface{}{
+		"response_type":    "ephemeral",
+		"text":             text,
+		"replace_original": replaceOriginal,
+	}
+
+	requestParamsJSON, err := json.Marshal(requestParams)
+	if err != nil {
+		return nil, err
+	}
+
+	return slack.PostResponseURL(repo.context(), responseURL, string(requestParamsJSON))
+}
+
+// OpenSettingsView opens modal for user settings.
+func (repo *SlackRepository) OpenSettingsView(triggerID string, settings models.UserSettings) ([]byte, error) {
+	var resp []byte
+
+	postMode := settings.PostMode
+	if !routing.IsValidMode(postMode) {
+		postMode = validation.PostModeDefault
+	}
+
+	locale := settings.Locale
+	if !i18n.IsSupported(locale) {
+		locale = validation.LocaleDefault
+	}
+
+	viewParams := map[string]string{
+		"minDailyGoal":       strconv.Itoa(validation.MinDailyGoal),
+		"maxDailyGoal":       strconv.Itoa(validation.MaxDailyGoal),
+		"initialDailyGoal":   strconv.FormatInt(settings.DailyGoal, 10),
+		"initialPostMode":    postMode,
+		"initialPostChannel": settings.PostChannel,
+		"initialLocale":      locale,
+	}
+
+	view, err := repo.renderView("settings_form.json", viewParams)
+	if err != nil {
+		return resp, err
+	}
+
+	// conversations_select does not accept empty initial conversation.
+	if len(settings.PostChannel) == 0 {
+		view, err = jsonpointer.Remove(view, "/blocks/2/element/initial_conversation")
+		if err != nil {
+			return resp, err
+		}
+	}
+
+	return repo.openRenderedView(triggerID, view)
+}
+
+// OpenDirectMessage opens DM with user and returns its channel ID.
+func (repo *SlackRepository) OpenDirectMessage(userID string) (string, error) {
+	var conversation struct {
+		Ok      bool   `json:"ok"`
+		Error   string `json:"error"`
+		Channel struct {
+			ID string `json:"id"`
+		} `json:"channel"`
+	}
+
+	resp, err := slack.PostJSON(repo.context(), repo.Token, "conversations.open", "application/x-www-form-urlencoded", "users="+url.QueryEscape(userID))
+	if err != nil {
+		return "", err
+	}
+
+	err = json.Unmarshal(resp, &conversation)
+	if err != nil {
+		return "", err
+	}
+
+	if !conversation.Ok {
+		return "", fmt.Errorf("Failed opening DM: %s", conversation.Error)
+	}
+
+	return conversation.Channel.ID, nil
+}
+
+// FetchUserName returns name of user from user ID.
+func (repo *SlackRepository) FetchUserName(userID string) (string, error) {
+	var userInfo struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+		User  struct {
+			Name string `json:"name"`
+		} `json:"user"`
+	}
+
+	resp, err := slack.PostJSON(repo.context(), repo.Token, "users.info", "application/x-www-form-urlencoded", "user="+url.QueryEscape(userID))
+	if err != nil {
+		return "", err
+	}
+
+	err = json.Unmarshal(resp, &userInfo)
+	if err != nil {
+		return "", err
+	}
+
+	if !userInfo.Ok {
+		return "", fmt.Errorf("Failed fetching user info: %s", userInfo.Error)
+	}
+
+	return userInfo.User.Name, nil
+}
+
+// AuthTest checks that token is valid.
+func (repo *SlackRepository) AuthTest() error {
+	resp, err := slack.PostJSON(repo.context(), repo.Token, "auth.test", "application/x-www-form-urlencoded", "")
+	if err != nil {
+		return err
+	}
+
+	var result slack.Response
+	err = json.Unmarshal(resp, &result)
+	if err != nil {
+		return err
+	}
+
+	if !result.Ok {
+		return fmt.Errorf("Failed auth test: %s", result.Error)
+	}
+
+	return nil
+}
+
+// FetchUserLocale returns locale of user set in Slack such as "en-US".
+func (repo *SlackRepository) FetchUserLocale(userID string) (string, error) {
+	var userInfo struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+		User  struct {
+			Locale string `json:"locale"`
+		} `json:"user"`
+	}
+
+	resp, err := slack.PostJSON(repo.context(), repo.Token, "users.info", "application/x-www-form-urlencoded", "include_locale=true&user="+url.QueryEscape(userID))
+	if err != nil {
+		return "", err
+	}
+
+	err = json.Unmarshal(resp, &userInfo)
+	if err != nil {
+		return "", err
+	}
+
+	if !userInfo.Ok {
+		return "", fmt.Errorf("Failed fetching user info: %s", userInfo.Error)
+	}
+
+	return userInfo.User.Locale, nil
+}
+
+// FetchUserTimeZone returns time zone of user set in Slack such as "Asia/Tokyo".
+func (repo *SlackRepository) FetchUserTimeZone(userID string) (string, error) {
+	var userInfo struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+		User  struct {
+			TZ string `json:"tz"`
+		} `json:"user"`
+	}
+
+	resp, err := slack.PostJSON(repo.context(), repo.Token, "users.info", "application/x-www-form-urlencoded", "user="+url.QueryEscape(userID))
+	if err != nil {
+		return "", err
+	}
+
+	err = json.Unmarshal(resp, &userInfo)
+	if err != nil {
+		return "", err
+	}
+
+	if !userInfo.Ok {
+		return "", fmt.Errorf("Failed fetching user info: %s", userInfo.Error)
+	}
+
+	return userInfo.User.TZ, nil
+}
+
+// FetchFile returns info of shared file.
+func (repo *SlackRepository) FetchFile(fileID string) (slack.File, error) {
+	var fileInfo struct {
+		Ok    bool       `json:"ok"`
+		Error string     `json:"error"`
+		File  slack.File `json:"file"`
+	}
+
+	resp, err := slack.PostJSON(repo.context(), repo.Token, "files.info", "application/x-www-form-urlencoded", "file="+url.QueryEscape(fileID))
+	if err != nil {
+		return fileInfo.File, err
+	}
+
+	err = json.Unmarshal(resp, &fileInfo)
+	if err != nil {
+		return fileInfo.File, err
+	}
+
+	if !fileInfo.Ok {
+		return fileInfo.File, fmt.Errorf("Failed fetching file info: %s", fileInfo.Error)
+	}
+
+	return fileInfo.File, nil
+}
+
+// DownloadFile returns content of shared file. slack.ErrFileTooLarge is returned if file is larger than maxSize.
+func (repo *SlackRepository) DownloadFile(file slack.File, maxSize int64) ([]byte, error) {
+	return slack.Download(repo.context(), repo.Token, file.URLPrivateDownload, maxSize)
+}
+
+// renderFavoriteButtons returns quick logging buttons of favorite drinks.
+func (repo *SlackRepository) renderFavoriteButtons(favorites []models.FavoriteDrink) ([]interface{}, error) {
+	var buttonList []interface{}
+
+	for i, favorite := range favorites {
+		buttonParams := favoriteViewParams(favorite)
+		buttonParams["index"] = strconv.Itoa(i)
+
+		button, err := repo.renderView("favorite_button.json", buttonParams)
+		if err != nil {
+			return buttonList, err
+		}
+		buttonList = append(buttonList, button)
+	}
+
+	return buttonList, nil
+}
+
+// undoViewParams returns template params of undo message.
+func undoViewParams(locale string, revision models.HydrationRevision) map[string]string {
+	text := ""
+	switch revision.Operation {
+	case models.OperationAdd:
+		text = i18n.T(locale, "undo.add", revision.Drink)
+	case models.OperationUpdate:
+		text = i18n.T(locale, "undo.update", revision.Drink)
+	case models.OperationDelete:
+		text = i18n.T(locale, "undo.delete", revision.Drink)
+	}
+
+	return map[string]string{
+		"text":       text,
+		"revisionID": strconv.FormatInt(revision.ID, 10),
+	}
+}
+
+// formatDrankAt returns time of drink saved without time zone as shown in messages in loc.
+func formatDrankAt(drankAt time.Time, loc *time.Location) string {
+	return export.InLocation(drankAt, loc).Format("2006/01/02 15:04")
+}
+
+// favoriteViewParams returns template params of favorite drink.
+func favoriteViewParams(favorite models.FavoriteDrink) map[string]string {
+	drink := []rune(favorite.Drink)
+	if len(drink) > 30 {
+		drink = append(drink[:29], '…')
+	}
+
+	return map[string]string{
+		"drink":  string(drink),
+		"amount": strconv.FormatInt(favorite.Amount, 10),
+		"key":    favorite.Key(),
+	}
+}
+
+// renderView renders view template and returns decoded view.
+func (repo *SlackRepository) renderView(viewName string, viewParams map[string]string) (interface{}, error) {
+	if repo.Views == nil {
+		return nil, fmt.Errorf("View templates are not loaded: %s", viewName)
+	}
+
+	return repo.Views.Render(viewName, repo.Locale, viewParams)
+}
+
+// openView opens modal from template.
+func (repo *SlackRepository) openView(triggerID string, viewName string, viewParams map[string]string) ([]byte, error) {
+	var resp []byte
+
+	view, err := repo.renderView(viewName, viewParams)
+	if err != nil {
+		return resp, err
+	}
+
+	return repo.openRenderedView(triggerID, view)
+}
+
+// openRenderedView opens modal from decoded view.
+func (repo *SlackRepository) openRenderedView(triggerID string, view interface{}) ([]byte, error) {
+	var err error
+	var resp []byte
+	var requestParams interface{}
+
+	err = views.Validate(view)
+	if err != nil {
+		return resp, err
+	}
+
+	requestJSON := `{"trigger_id": "", "view": {}}`
+	err = json.Unmarshal([]byte(requestJSON), &requestParams)
 	if err != nil {
 		return resp, err
 	}
@@ -326,19 +941,43 @@ func (repo *SlackRepository) openView(triggerID string, viewPath string, viewPar
 		return resp, err
 	}
 
-	//fmt.Println(string(requestParamsJSON))
-	resp, err = slack.PostJSON(repo.Token, "views.open", "application/json", string(requestParamsJSON))
-	//fmt.Println(string(resp))
+	resp, err = slack.PostJSON(repo.context(), repo.Token, "views.open", "application/json", string(requestParamsJSON))
 
 	return resp, err
 }
 
-func replaceViewTemplateParams(srcText string, params map[string]string) string {
-	destText := srcText
+// progressBar returns text bar filled by percent.
+func progressBar(percent int64, width int64) string {
+	filled := percent * width / 100
+	if filled > width {
+		filled = width
+	}
+	if filled < 0 {
+		filled = 0
+	}
+
+	return strings.Repeat("█", int(filled)) + strings.Repeat("░", int(width-filled))
+}
+
+// sparkline returns text chart of values.
+func sparkline(values []int64) string {
+	levels := []rune("▁▂▃▄▅▆▇█")
+
+	var maxValue int64
+	for _, value := range values {
+		if value > maxValue {
+			maxValue = value
+		}
+	}
 
-	for k, v := range params {
-		destText = strings.ReplaceAll(destText, "{{"+k+"}}", v)
+	var line []rune
+	for _, value := range values {
+		level := 0
+		if maxValue > 0 {
+			level = int(value * int64(len(levels)-1) / maxValue)
+		}
+		line = append(line, levels[level])
 	}
 
-	return destText
+	return string(line)
 }
@@ -0,0 +1,243 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/metrics"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/slack"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Outcomes of interactions passed to Observer.
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+)
+
+type (
+	// UserLookupFunc resolves user name from user ID.
+	UserLookupFunc func(ctx context.Context, userID string) (string, error)
+
+	// LocaleLookupFunc resolves locale of user who triggered interaction.
+	LocaleLookupFunc func(ctx context.Context, user slack.User, userName string) string
+
+	// ClaimFunc records delivery ID and returns false if it is already recorded.
+	ClaimFunc func(ctx context.Context, deliveryID string) (bool, error)
+
+	// ReleaseFunc deletes record of delivery ID so that retry of delivery is handled.
+	ReleaseFunc func(ctx context.Context, deliveryID string) error
+
+	// Observer records outcome and duration of handled interaction.
+	Observer func(route Route, outcome string, elapsed time.Duration)
+)
+
+// Recover responds with 500 instead of crashing when handler panics.
+func Recover() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c echo.Context, interaction slack.Interaction) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					stack := make([]byte, 4096)
+					stack = stack[:runtime.Stack(stack, false)]
+					slog.ErrorContext(c.Request().Context(), "Panic in handler",
+						"callback_id", interaction.RouteID(), "panic", fmt.Sprint(r), "stack", string(stack))
+					err = c.String(http.StatusInternalServerError, "Error")
+				}
+			}()
+
+			return next(c, interaction)
+		}
+	}
+}
+
+// Logger logs handled interaction with its user and duration.
+func Logger() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c echo.Context, interaction slack.Interaction) error {
+			start := time.Now()
+			err := next(c, interaction)
+
+			common := interaction.Common()
+			attrs := []any{
+				"payload_type", common.Type,
+				"callback_id", interaction.RouteID(),
+				"user_id", common.User.ID,
+				"status", c.Response().Status,
+				"duration_ms", time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				slog.ErrorContext(c.Request().Context(), "Failed handling interaction", append(attrs, "error", err)...)
+			} else {
+				slog.InfoContext(c.Request().Context(), "Handled interaction", attrs...)
+			}
+
+			return err
+		}
+	}
+}
+
+// Trace starts span of interaction named after matched route, so that spans of handler and its background jobs
+// are grouped under it.
+func Trace() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c echo.Context, interaction slack.Interaction) error {
+			route := MatchedRoute(c)
+			common := interaction.Common()
+
+			ctx, span := tracing.Start(c.Request().Context(), "interaction "+route.PayloadType+" "+route.ID,
+				attribute.String("slack.payload_type", common.Type),
+				attribute.String("slack.callback_id", interaction.RouteID()),
+				attribute.String("slack.team_id", common.Team.ID),
+				attribute.String("slack.user_id", common.User.ID),
+			)
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			err := next(c, interaction)
+
+			spanErr := err
+			if spanErr == nil && c.Response().Status >= http.StatusBadRequest {
+				spanErr = fmt.Errorf("Interaction answered %d", c.Response().Status)
+			}
+			tracing.End(span, spanErr)
+
+			return err
+		}
+	}
+}
+
+// VerifySignature rejects requests whose Slack signature does not match signing secret.
+// It is echo middleware, so that interactions, events and slash commands are verified alike before their bodies
+// are read. All requests are rejected if signing secret is empty. Verified body is kept for RawBody.
+func VerifySignature(signingSecret string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := c.Request().Context()
+
+			if len(signingSecret) == 0 {
+				slog.ErrorContext(ctx, "Rejected request as signing secret is not set", "path", c.Path())
+				return c.String(http.StatusUnauthorized, "Error")
+			}
+
+			body, err := ioutil.ReadAll(c.Request().Body)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed reading request body", "error", err)
+				return c.String(http.StatusBadRequest, "Error")
+			}
+			c.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+			c.Set(keyRawBody, body)
+
+			if err := slack.VerifySignature(signingSecret, c.Request().Header, body, time.Now()); err != nil {
+				slog.WarnContext(ctx, "Rejected request", "path", c.Path(), "error", err)
+				return c.String(http.StatusUnauthorized, "Error")
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// Deduplicate acknowledges deliveries which are already received without calling handler,
+// so that retries of Slack do not repeat side effects. Requests must be verified with VerifySignature before it.
+// Handler is called if claim fails, as losing interaction is worse than handling it twice.
+// Claim is released with release when handler fails with error, 5xx or panic, so that retry of Slack is handled.
+func Deduplicate(claim ClaimFunc, release ReleaseFunc) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c echo.Context, interaction slack.Interaction) error {
+			deliveryID := interaction.DeliveryID()
+			if len(deliveryID) == 0 {
+				return next(c, interaction)
+			}
+
+			claimed, err := claim(c.Request().Context(), deliveryID)
+			if err != nil {
+				slog.ErrorContext(c.Request().Context(), "Failed checking duplicate delivery", "error", err)
+				return next(c, interaction)
+			}
+
+			if !claimed {
+				slog.InfoContext(c.Request().Context(), "Ignored duplicate delivery",
+					"callback_id", interaction.RouteID(),
+					"retry_num", c.Request().Header.Get(slack.HeaderRetryNum),
+					"retry_reason", c.Request().Header.Get(slack.HeaderRetryReason))
+				metrics.ObserveDuplicateDelivery(interaction.Common().Type)
+				return c.NoContent(http.StatusOK)
+			}
+
+			// claim is also released when handler panics, before Recover answers 500.
+			handled := false
+			defer func() {
+				if handled {
+					return
+				}
+				if err := release(c.Request().Context(), deliveryID); err != nil {
+					slog.ErrorContext(c.Request().Context(), "Failed releasing delivery", "error", err)
+				}
+			}()
+
+			err = next(c, interaction)
+			handled = err == nil && c.Response().Status < http.StatusInternalServerError
+			return err
+		}
+	}
+}
+
+// UserLookup resolves user name with lookup when payload does not have it.
+// Handlers get resolved name with UserName.
+func UserLookup(lookup UserLookupFunc) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c echo.Context, interaction slack.Interaction) error {
+			user := interaction.Common().User
+			userName := user.UserName()
+
+			if len(userName) == 0 {
+				var err error
+				userName, err = lookup(c.Request().Context(), user.ID)
+				if err != nil {
+					slog.ErrorContext(c.Request().Context(), "Failed looking up user name", "error", err)
+					return c.String(http.StatusInternalServerError, "Error")
+				}
+			}
+			c.Set(keyUserName, userName)
+
+			return next(c, interaction)
+		}
+	}
+}
+
+// LocaleLookup resolves locale of user with lookup. It must run after UserLookup.
+// Handlers get resolved locale with Locale.
+func LocaleLookup(lookup LocaleLookupFunc) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c echo.Context, interaction slack.Interaction) error {
+			c.Set(keyLocale, lookup(c.Request().Context(), interaction.Common().User, UserName(c, interaction)))
+			return next(c, interaction)
+		}
+	}
+}
+
+// Metrics passes outcome and duration of each interaction to observer.
+func Metrics(observe Observer) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c echo.Context, interaction slack.Interaction) error {
+			start := time.Now()
+			err := next(c, interaction)
+
+			outcome := OutcomeOK
+			if err != nil || c.Response().Status >= http.StatusBadRequest {
+				outcome = OutcomeError
+			}
+			observe(MatchedRoute(c), outcome, time.Since(start))
+
+			return err
+		}
+	}
+}
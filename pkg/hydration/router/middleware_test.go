@@ -0,0 +1,118 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/slack"
+)
+
+// viewSubmission returns view submission payload of record form with amount.
+func viewSubmission(amount string) string {
+	return `{
+		"type": "view_submission",
+		"team": {"id": "T1"},
+		"user": {"id": "U1", "username": "alice"},
+		"view": {
+			"id": "V1",
+			"callback_id": "hydration__record_form",
+			"hash": "1.abc",
+			"state": {"values": {"amount": {"amount": {"type": "number_input", "value": "` + amount + `"}}}}
+		}
+	}`
+}
+
+func TestDeduplicateViewResubmission(t *testing.T) {
+	claimed := map[string]bool{}
+	claim := func(ctx context.Context, deliveryID string) (bool, error) {
+		if claimed[deliveryID] {
+			return false, nil
+		}
+		claimed[deliveryID] = true
+		return true, nil
+	}
+
+	release := func(ctx context.Context, deliveryID string) error {
+		delete(claimed, deliveryID)
+		return nil
+	}
+
+	handled := 0
+	r := New()
+	r.Use(Deduplicate(claim, release))
+	r.Handle(slack.InteractionViewSubmission, "hydration__record_form", func(c echo.Context, interaction slack.Interaction) error {
+		handled++
+		return c.String(http.StatusOK, "")
+	})
+
+	steps := []struct {
+		name    string
+		amount  string
+		handled int
+	}{
+		{"first submission", "abc", 1},
+		{"retry of same submission", "abc", 1},
+		{"resubmission after errors", "200", 2},
+	}
+
+	for _, step := range steps {
+		rec := serve(t, r, viewSubmission(step.amount))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", step.name, rec.Code, http.StatusOK)
+		}
+		if handled != step.handled {
+			t.Errorf("%s: handler called %d times, want %d", step.name, handled, step.handled)
+		}
+	}
+}
+
+func TestDeduplicateReleasesFailedDelivery(t *testing.T) {
+	claimed := map[string]bool{}
+	claim := func(ctx context.Context, deliveryID string) (bool, error) {
+		if claimed[deliveryID] {
+			return false, nil
+		}
+		claimed[deliveryID] = true
+		return true, nil
+	}
+	release := func(ctx context.Context, deliveryID string) error {
+		delete(claimed, deliveryID)
+		return nil
+	}
+
+	handled := 0
+	r := New()
+	r.Use(Recover(), Deduplicate(claim, release))
+	r.Handle(slack.InteractionViewSubmission, "hydration__record_form", func(c echo.Context, interaction slack.Interaction) error {
+		handled++
+		switch handled {
+		case 1:
+			return c.String(http.StatusInternalServerError, "Error")
+		case 2:
+			panic("handler failed")
+		}
+		return c.String(http.StatusOK, "")
+	})
+
+	steps := []struct {
+		name   string
+		status int
+	}{
+		{"first delivery fails", http.StatusInternalServerError},
+		{"retry after failure panics", http.StatusInternalServerError},
+		{"retry after panic", http.StatusOK},
+		{"retry after success", http.StatusOK},
+	}
+
+	for _, step := range steps {
+		rec := serve(t, r, viewSubmission("200"))
+		if rec.Code != step.status {
+			t.Errorf("%s: status = %d, want %d", step.name, rec.Code, step.status)
+		}
+	}
+	if handled != 3 {
+		t.Errorf("handler called %d times, want 3", handled)
+	}
+}
@@ -0,0 +1,170 @@
+package router
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/slack"
+)
+
+// Keys of values which router and middlewares store in echo.Context.
+const (
+	keyRawBody  = "router.raw_body"
+	keyRoute    = "router.route"
+	keyUserName = "router.user_name"
+	keyLocale   = "router.locale"
+)
+
+type (
+	// HandlerFunc handles interaction payload.
+	HandlerFunc func(c echo.Context, interaction slack.Interaction) error
+
+	// Middleware wraps handler with common processing.
+	Middleware func(next HandlerFunc) HandlerFunc
+
+	// Route describes which interactions handler is registered for.
+	Route struct {
+		PayloadType string
+		ID          string
+		Prefix      bool
+	}
+
+	route struct {
+		Route
+		handler HandlerFunc
+	}
+
+	// Router dispatches interaction payloads to handlers registered by payload type and callback or action ID.
+	Router struct {
+		routes      []route
+		middlewares []Middleware
+	}
+)
+
+// New returns empty router.
+func New() *Router {
+	return &Router{}
+}
+
+// Use adds middlewares applied to all handlers. Middlewares added first run first.
+func (r *Router) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// Handle registers handler for payload type and callback or action ID.
+// Middlewares given here run after middlewares added with Use.
+func (r *Router) Handle(payloadType string, id string, handler HandlerFunc, middlewares ...Middleware) {
+	r.add(Route{PayloadType: payloadType, ID: id}, handler, middlewares)
+}
+
+// HandlePrefix registers handler for payload type and callback or action IDs starting with prefix.
+// Exact routes take precedence over prefix routes and longer prefixes take precedence over shorter ones.
+func (r *Router) HandlePrefix(payloadType string, prefix string, handler HandlerFunc, middlewares ...Middleware) {
+	r.add(Route{PayloadType: payloadType, ID: prefix, Prefix: true}, handler, middlewares)
+}
+
+func (r *Router) add(routeInfo Route, handler HandlerFunc, middlewares []Middleware) {
+	r.routes = append(r.routes, route{
+		Route:   routeInfo,
+		handler: chain(handler, middlewares),
+	})
+}
+
+// match returns route for interaction.
+func (r *Router) match(payloadType string, id string) (route, bool) {
+	var matched route
+	found := false
+
+	for _, candidate := range r.routes {
+		if candidate.PayloadType != payloadType {
+			continue
+		}
+
+		if !candidate.Prefix {
+			if candidate.ID == id {
+				return candidate, true
+			}
+			continue
+		}
+
+		if strings.HasPrefix(id, candidate.ID) && (!found || len(candidate.ID) > len(matched.ID)) {
+			matched = candidate
+			found = true
+		}
+	}
+
+	return matched, found
+}
+
+// Serve decodes interaction payload of request and calls handler registered for it.
+func (r *Router) Serve(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	body, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed reading request body", "error", err)
+		return c.String(http.StatusBadRequest, "Error")
+	}
+	c.Request().Body = ioutil.NopCloser(bytes.NewReader(body))
+	c.Set(keyRawBody, body)
+
+	payloadJSON := c.FormValue("payload")
+
+	interaction, err := slack.ParseInteraction([]byte(payloadJSON))
+	if err != nil {
+		slog.WarnContext(ctx, "Invalid interaction payload", "error", err)
+		return c.String(http.StatusBadRequest, "Error")
+	}
+
+	payloadType := interaction.Common().Type
+	id := interaction.RouteID()
+
+	matched, ok := r.match(payloadType, id)
+	if !ok {
+		slog.WarnContext(ctx, "Unrecognized interaction", "payload_type", payloadType, "callback_id", id)
+		return c.String(http.StatusForbidden, "Error")
+	}
+	c.Set(keyRoute, matched.Route)
+
+	return chain(matched.handler, r.middlewares)(c, interaction)
+}
+
+// chain wraps handler with middlewares so that first middleware runs first.
+func chain(handler HandlerFunc, middlewares []Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// RawBody returns request body read by router.
+func RawBody(c echo.Context) []byte {
+	body, _ := c.Get(keyRawBody).([]byte)
+	return body
+}
+
+// MatchedRoute returns route which matched current interaction.
+func MatchedRoute(c echo.Context) Route {
+	matched, _ := c.Get(keyRoute).(Route)
+	return matched
+}
+
+// UserName returns user name resolved by UserLookup middleware.
+// User name in payload is returned if middleware is not used.
+func UserName(c echo.Context, interaction slack.Interaction) string {
+	if userName, ok := c.Get(keyUserName).(string); ok {
+		return userName
+	}
+	return interaction.Common().User.UserName()
+}
+
+// Locale returns locale resolved by LocaleLookup middleware.
+// Empty string is returned if middleware is not used.
+func Locale(c echo.Context) string {
+	locale, _ := c.Get(keyLocale).(string)
+	return locale
+}
@@ -0,0 +1,103 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/slack"
+)
+
+// serve posts interaction payload to router and returns recorded response.
+func serve(t *testing.T, r *Router, payloadJSON string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	form := url.Values{"payload": {payloadJSON}}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+
+	if err := r.Serve(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatalf("Serve returned error: %v", err)
+	}
+	return rec
+}
+
+func TestServeMalformedPayload(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("..", "slack", "testdata", "malformed", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no malformed fixtures: %v", err)
+	}
+
+	handled := false
+	r := New()
+	r.HandlePrefix(slack.InteractionBlockActions, "", func(c echo.Context, interaction slack.Interaction) error {
+		handled = true
+		return c.String(http.StatusOK, "")
+	})
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			payloadJSON, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec := serve(t, r, string(payloadJSON))
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+			if handled {
+				t.Error("handler was called")
+			}
+		})
+	}
+}
+
+func TestServeFixtures(t *testing.T) {
+	tests := []struct {
+		fixture     string
+		payloadType string
+		id          string
+	}{
+		{"shortcut.json", slack.InteractionShortcut, "hydration__record_drink"},
+		{"message_action.json", slack.InteractionMessageAction, "hydration__favorites"},
+		{"block_actions.json", slack.InteractionBlockActions, "hydration__repeat_drink"},
+		{"view_submission.json", slack.InteractionViewSubmission, "hydration__record_form"},
+		{"view_closed.json", slack.InteractionViewClosed, "hydration__settings_form"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			payloadJSON, err := os.ReadFile(filepath.Join("..", "slack", "testdata", test.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var matched Route
+			r := New()
+			r.Handle(test.payloadType, test.id, func(c echo.Context, interaction slack.Interaction) error {
+				matched = MatchedRoute(c)
+				return c.String(http.StatusOK, "")
+			})
+
+			rec := serve(t, r, string(payloadJSON))
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+			}
+			if matched.ID != test.id {
+				t.Errorf("matched route = %+v, want ID %q", matched, test.id)
+			}
+
+			rec = serve(t, New(), string(payloadJSON))
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("status without route = %d, want %d", rec.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package routing
+
+import (
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/config"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+)
+
+// Modes of routing result messages.
+const (
+	ModeChannel = "channel"
+	ModeDM      = "dm"
+	ModeOrigin  = "origin"
+)
+
+// DefaultChannel is used when no channel is configured.
+const DefaultChannel = "#general"
+
+// Destination describes candidates of channel where result message is posted.
+type Destination struct {
+	// SelectedChannel is channel selected on record form.
+	SelectedChannel string
+	// OriginChannel is channel where shortcut or button was used.
+	OriginChannel string
+	// UserID is used for posting to DM.
+	UserID string
+}
+
+// IsValidMode returns whether mode is known routing mode.
+func IsValidMode(mode string) bool {
+	switch mode {
+	case ModeChannel, ModeDM, ModeOrigin:
+		return true
+	}
+	return false
+}
+
+// ResultChannel returns channel where result message is posted.
+// Channel selected on form has priority, then user settings, then workspace rule.
+func ResultChannel(rule config.Routing, settings models.UserSettings, destination Destination) string {
+	if len(destination.SelectedChannel) > 0 {
+		return destination.SelectedChannel
+	}
+
+	mode := rule.Mode
+	channel := rule.Channel
+	if IsValidMode(settings.PostMode) {
+		mode = settings.PostMode
+		if len(settings.PostChannel) > 0 {
+			channel = settings.PostChannel
+		}
+	}
+	if len(channel) == 0 {
+		channel = DefaultChannel
+	}
+
+	userID := destination.UserID
+	if len(userID) == 0 {
+		userID = settings.UserID
+	}
+
+	switch mode {
+	case ModeDM:
+		if len(userID) > 0 {
+			return userID
+		}
+	case ModeOrigin:
+		if len(destination.OriginChannel) > 0 {
+			return destination.OriginChannel
+		}
+	}
+
+	return channel
+}
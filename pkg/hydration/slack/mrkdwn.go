@@ -0,0 +1,22 @@
+package slack
+
+import "strings"
+
+// zeroWidthSpace is inserted before formatting characters so that Slack does not treat them as markup.
+const zeroWidthSpace = "\u200b"
+
+var mrkdwnReplacer = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"*", zeroWidthSpace+"*",
+	"_", zeroWidthSpace+"_",
+	"~", zeroWidthSpace+"~",
+	"`", zeroWidthSpace+"`",
+)
+
+// EscapeMrkdwn escapes text so that it is shown as is in mrkdwn text.
+// Control sequences such as <!channel> and links are escaped, and formatting characters are neutralized.
+func EscapeMrkdwn(text string) string {
+	return mrkdwnReplacer.Replace(text)
+}
@@ -0,0 +1,356 @@
+package slack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Headers which Slack adds to retried deliveries.
+const (
+	HeaderRetryNum    = "X-Slack-Retry-Num"
+	HeaderRetryReason = "X-Slack-Retry-Reason"
+)
+
+// Types of interaction payloads.
+const (
+	InteractionShortcut       = "shortcut"
+	InteractionMessageAction  = "message_action"
+	InteractionBlockActions   = "block_actions"
+	InteractionViewSubmission = "view_submission"
+	InteractionViewClosed     = "view_closed"
+)
+
+type (
+	// User describes user who triggered interaction.
+	// Username is empty for message_action payloads, which only have Name.
+	// Locale is set only if the app requests locale of users.
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Name     string `json:"name"`
+		TeamID   string `json:"team_id"`
+		Locale   string `json:"locale"`
+	}
+
+	// Team describes workspace where interaction happened.
+	Team struct {
+		ID     string `json:"id"`
+		Domain string `json:"domain"`
+	}
+
+	// Channel describes channel where interaction happened.
+	Channel struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	// Container describes message or view which contains clicked element.
+	Container struct {
+		Type      string `json:"type"`
+		MessageTS string `json:"message_ts"`
+		ChannelID string `json:"channel_id"`
+		ViewID    string `json:"view_id"`
+	}
+
+	// Action describes clicked or selected block element.
+	Action struct {
+		Type     string `json:"type"`
+		ActionID string `json:"action_id"`
+		BlockID  string `json:"block_id"`
+		Value    string `json:"value"`
+		ActionTS string `json:"action_ts"`
+	}
+
+	// Option describes option of select elements.
+	Option struct {
+		Value string `json:"value"`
+	}
+
+	// ViewStateValue describes value of one input element.
+	ViewStateValue struct {
+		Type                 string  `json:"type"`
+		Value                string  `json:"value"`
+		SelectedDate         string  `json:"selected_date"`
+		SelectedTime         string  `json:"selected_time"`
+		SelectedConversation string  `json:"selected_conversation"`
+		SelectedOption       *Option `json:"selected_option"`
+	}
+
+	// ViewState describes input values of view keyed by block ID and action ID.
+	ViewState struct {
+		Values map[string]map[string]ViewStateValue `json:"values"`
+	}
+
+	// View describes modal or home view.
+	View struct {
+		ID              string    `json:"id"`
+		Type            string    `json:"type"`
+		CallbackID      string    `json:"callback_id"`
+		PrivateMetadata string    `json:"private_metadata"`
+		Hash            string    `json:"hash"`
+		State           ViewState `json:"state"`
+	}
+
+	// PayloadMessage describes message which message shortcut was used on.
+	PayloadMessage struct {
+		TS   string `json:"ts"`
+		Text string `json:"text"`
+	}
+
+	// InteractionCommon describes fields shared by interaction payloads.
+	// Channel is empty for payloads which are not sent from channels.
+	InteractionCommon struct {
+		Type      string  `json:"type"`
+		TriggerID string  `json:"trigger_id"`
+		User      User    `json:"user"`
+		Team      Team    `json:"team"`
+		Channel   Channel `json:"channel"`
+	}
+
+	// ShortcutPayload is sent when global shortcut is used.
+	ShortcutPayload struct {
+		InteractionCommon
+		CallbackID string `json:"callback_id"`
+		ActionTS   string `json:"action_ts"`
+	}
+
+	// MessageActionPayload is sent when message shortcut is used.
+	MessageActionPayload struct {
+		InteractionCommon
+		CallbackID  string         `json:"callback_id"`
+		ResponseURL string         `json:"response_url"`
+		Message     PayloadMessage `json:"message"`
+		ActionTS    string         `json:"action_ts"`
+	}
+
+	// BlockActionsPayload is sent when block element is clicked or selected.
+	// View is nil for actions in messages.
+	BlockActionsPayload struct {
+		InteractionCommon
+		ResponseURL string    `json:"response_url"`
+		Container   Container `json:"container"`
+		Actions     []Action  `json:"actions"`
+		View        *View     `json:"view"`
+	}
+
+	// ViewSubmissionPayload is sent when modal is submitted.
+	ViewSubmissionPayload struct {
+		InteractionCommon
+		View View `json:"view"`
+	}
+
+	// ViewClosedPayload is sent when modal is closed.
+	ViewClosedPayload struct {
+		InteractionCommon
+		View      View `json:"view"`
+		IsCleared bool `json:"is_cleared"`
+	}
+
+	// File describes file shared in Slack.
+	// User is ID of user who uploaded file, and URLPrivateDownload needs bot token to download.
+	File struct {
+		ID                 string `json:"id"`
+		Name               string `json:"name"`
+		User               string `json:"user"`
+		Size               int64  `json:"size"`
+		URLPrivateDownload string `json:"url_private_download"`
+	}
+
+	// Event describes event of Events API.
+	// Subtype, Text, BotID and Files are set only for message events.
+	Event struct {
+		Type        string `json:"type"`
+		Subtype     string `json:"subtype"`
+		User        string `json:"user"`
+		BotID       string `json:"bot_id"`
+		Channel     string `json:"channel"`
+		ChannelType string `json:"channel_type"`
+		Text        string `json:"text"`
+		Files       []File `json:"files"`
+		Tab         string `json:"tab"`
+	}
+
+	// EventPayload is sent to Events API request URL.
+	// Challenge is set only for url_verification requests.
+	EventPayload struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		TeamID    string `json:"team_id"`
+		EventID   string `json:"event_id"`
+		Event     Event  `json:"event"`
+	}
+
+	// Interaction is implemented by all interaction payloads.
+	Interaction interface {
+		// Common returns fields shared by interaction payloads.
+		Common() InteractionCommon
+		// RouteID returns callback ID or action ID which selects handler.
+		RouteID() string
+		// DeliveryID returns ID which is same among retries of delivery.
+		// Empty string is returned if payload has no such ID.
+		DeliveryID() string
+	}
+)
+
+// ErrInvalidPayload is returned when payload lacks required fields.
+var ErrInvalidPayload = errors.New("invalid interaction payload")
+
+// UserName returns username falling back to name.
+func (user User) UserName() string {
+	if len(user.Username) > 0 {
+		return user.Username
+	}
+	return user.Name
+}
+
+// Value returns value of input element. Zero value is returned if element does not exist.
+func (state ViewState) Value(blockID string, actionID string) ViewStateValue {
+	return state.Values[blockID][actionID]
+}
+
+// digest returns short hash of input values. Keys of maps are marshaled in sorted order, so it is same for same values.
+func (state ViewState) digest() string {
+	valuesJSON, _ := json.Marshal(state.Values)
+	sum := sha256.Sum256(valuesJSON)
+	return hex.EncodeToString(sum[:8])
+}
+
+// SelectedValue returns value of selected option. Empty string is returned if nothing is selected.
+func (value ViewStateValue) SelectedValue() string {
+	if value.SelectedOption == nil {
+		return ""
+	}
+	return value.SelectedOption.Value
+}
+
+// Common returns fields shared by interaction payloads.
+func (common InteractionCommon) Common() InteractionCommon {
+	return common
+}
+
+// RouteID returns callback ID of shortcut.
+func (payload ShortcutPayload) RouteID() string {
+	return payload.CallbackID
+}
+
+// RouteID returns callback ID of message shortcut.
+func (payload MessageActionPayload) RouteID() string {
+	return payload.CallbackID
+}
+
+// RouteID returns action ID of first action.
+func (payload BlockActionsPayload) RouteID() string {
+	return payload.Actions[0].ActionID
+}
+
+// RouteID returns callback ID of submitted view.
+func (payload ViewSubmissionPayload) RouteID() string {
+	return payload.View.CallbackID
+}
+
+// RouteID returns callback ID of closed view.
+func (payload ViewClosedPayload) RouteID() string {
+	return payload.View.CallbackID
+}
+
+// deliveryID joins type, team and user of interaction with ID of action or view.
+func deliveryID(common InteractionCommon, id string) string {
+	if len(id) == 0 {
+		return ""
+	}
+	return strings.Join([]string{common.Type, common.Team.ID, common.User.ID, id}, ":")
+}
+
+// DeliveryID returns ID of shortcut based on its action timestamp.
+func (payload ShortcutPayload) DeliveryID() string {
+	return deliveryID(payload.InteractionCommon, payload.ActionTS)
+}
+
+// DeliveryID returns ID of message shortcut based on its action timestamp.
+func (payload MessageActionPayload) DeliveryID() string {
+	return deliveryID(payload.InteractionCommon, payload.ActionTS)
+}
+
+// DeliveryID returns ID of block action based on action timestamp of first action.
+func (payload BlockActionsPayload) DeliveryID() string {
+	return deliveryID(payload.InteractionCommon, payload.Actions[0].ActionTS)
+}
+
+// DeliveryID returns ID of view submission based on view ID and hash, which changes whenever view is updated,
+// and submitted values. Hash stays same after form is answered with errors, so resubmission of corrected form
+// is told apart from retry by its values.
+func (payload ViewSubmissionPayload) DeliveryID() string {
+	if len(payload.View.ID) == 0 {
+		return ""
+	}
+	return deliveryID(payload.InteractionCommon, payload.View.ID+":"+payload.View.Hash+":"+payload.View.State.digest())
+}
+
+// DeliveryID returns ID of closed view based on view ID and hash.
+func (payload ViewClosedPayload) DeliveryID() string {
+	if len(payload.View.ID) == 0 {
+		return ""
+	}
+	return deliveryID(payload.InteractionCommon, payload.View.ID+":"+payload.View.Hash)
+}
+
+// DeliveryID returns ID of event callback. Empty string is returned for other requests.
+func (payload EventPayload) DeliveryID() string {
+	if len(payload.EventID) == 0 {
+		return ""
+	}
+	return "event:" + payload.TeamID + ":" + payload.EventID
+}
+
+// ParseInteraction decodes interaction payload into typed payload.
+func ParseInteraction(payloadJSON []byte) (Interaction, error) {
+	var common InteractionCommon
+	if err := json.Unmarshal(payloadJSON, &common); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	if len(common.User.ID) == 0 {
+		return nil, fmt.Errorf("%w: user is missing", ErrInvalidPayload)
+	}
+
+	var interaction Interaction
+	var err error
+
+	switch common.Type {
+	case InteractionShortcut:
+		var payload ShortcutPayload
+		err = json.Unmarshal(payloadJSON, &payload)
+		interaction = payload
+	case InteractionMessageAction:
+		var payload MessageActionPayload
+		err = json.Unmarshal(payloadJSON, &payload)
+		interaction = payload
+	case InteractionBlockActions:
+		var payload BlockActionsPayload
+		err = json.Unmarshal(payloadJSON, &payload)
+		if err == nil && len(payload.Actions) == 0 {
+			return nil, fmt.Errorf("%w: actions are missing", ErrInvalidPayload)
+		}
+		interaction = payload
+	case InteractionViewSubmission:
+		var payload ViewSubmissionPayload
+		err = json.Unmarshal(payloadJSON, &payload)
+		interaction = payload
+	case InteractionViewClosed:
+		var payload ViewClosedPayload
+		err = json.Unmarshal(payloadJSON, &payload)
+		interaction = payload
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidPayload, common.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+
+	return interaction, nil
+}
@@ -0,0 +1,154 @@
+package slack
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readFixture returns payload in testdata.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	payloadJSON, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payloadJSON
+}
+
+func TestParseInteraction(t *testing.T) {
+	tests := []struct {
+		fixture    string
+		routeID    string
+		deliveryID string
+		check      func(t *testing.T, interaction Interaction)
+	}{
+		{
+			fixture:    "shortcut.json",
+			routeID:    "hydration__record_drink",
+			deliveryID: "shortcut:T0000AAAA:U0000AAAA:1589268465.614381",
+			check: func(t *testing.T, interaction Interaction) {
+				payload := interaction.(ShortcutPayload)
+				if payload.TriggerID == "" || payload.User.UserName() != "alice" {
+					t.Errorf("unexpected payload: %+v", payload)
+				}
+			},
+		},
+		{
+			fixture:    "message_action.json",
+			routeID:    "hydration__favorites",
+			deliveryID: "message_action:T0000AAAA:U0000AAAA:1589268512.925302",
+			check: func(t *testing.T, interaction Interaction) {
+				payload := interaction.(MessageActionPayload)
+				if payload.User.UserName() != "alice" {
+					t.Errorf("user name = %q, want name used when username is missing", payload.User.UserName())
+				}
+				if payload.Channel.ID != "C0000AAAA" || payload.Message.TS != "1589268499.000200" || payload.ResponseURL == "" {
+					t.Errorf("unexpected payload: %+v", payload)
+				}
+			},
+		},
+		{
+			fixture:    "block_actions.json",
+			routeID:    "hydration__repeat_drink",
+			deliveryID: "block_actions:T0000AAAA:U0000AAAA:1589268540.871533",
+			check: func(t *testing.T, interaction Interaction) {
+				payload := interaction.(BlockActionsPayload)
+				if payload.Actions[0].Value != "42" || payload.Container.MessageTS != "1589268530.000300" || payload.View != nil {
+					t.Errorf("unexpected payload: %+v", payload)
+				}
+			},
+		},
+		{
+			fixture:    "view_submission.json",
+			routeID:    "hydration__record_form",
+			deliveryID: "view_submission:T0000AAAA:U0000AAAA:V0000AAAA:1589268551.Ab1Cd2Ef:",
+			check: func(t *testing.T, interaction Interaction) {
+				state := interaction.(ViewSubmissionPayload).View.State
+				if got := state.Value("drink", "drink").Value; got != "Green tea" {
+					t.Errorf("drink = %q", got)
+				}
+				if got := state.Value("amount", "amount").Value; got != "237" {
+					t.Errorf("amount = %q", got)
+				}
+				if got := state.Value("drank_date", "drank_date").SelectedDate; got != "2020-05-12" {
+					t.Errorf("date = %q", got)
+				}
+				if got := state.Value("drank_time", "drank_time").SelectedTime; got != "09:30" {
+					t.Errorf("time = %q", got)
+				}
+			},
+		},
+		{
+			fixture:    "view_closed.json",
+			routeID:    "hydration__settings_form",
+			deliveryID: "view_closed:T0000AAAA:U0000AAAA:V0000AAAA:1589268562.Gh3Ij4Kl",
+			check: func(t *testing.T, interaction Interaction) {
+				if interaction.(ViewClosedPayload).IsCleared {
+					t.Error("is_cleared = true")
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			interaction, err := ParseInteraction(readFixture(t, test.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := interaction.RouteID(); got != test.routeID {
+				t.Errorf("RouteID() = %q, want %q", got, test.routeID)
+			}
+			if got := interaction.DeliveryID(); !strings.HasPrefix(got, test.deliveryID) {
+				t.Errorf("DeliveryID() = %q, want prefix %q", got, test.deliveryID)
+			}
+			test.check(t, interaction)
+		})
+	}
+}
+
+func TestParseInteractionMalformed(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "malformed", "*.json"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no malformed fixtures: %v", err)
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			payloadJSON, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := ParseInteraction(payloadJSON); !errors.Is(err, ErrInvalidPayload) {
+				t.Errorf("error = %v, want ErrInvalidPayload", err)
+			}
+		})
+	}
+}
+
+func TestViewSubmissionDeliveryID(t *testing.T) {
+	interaction, err := ParseInteraction(readFixture(t, "view_submission.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	submitted := interaction.(ViewSubmissionPayload)
+
+	retried := submitted
+	if retried.DeliveryID() != submitted.DeliveryID() {
+		t.Error("retry of same submission has different delivery ID")
+	}
+
+	corrected := submitted
+	corrected.View.State = ViewState{Values: map[string]map[string]ViewStateValue{
+		"drink": {"drink": {Type: "plain_text_input", Value: "Water"}},
+	}}
+	if corrected.DeliveryID() == submitted.DeliveryID() {
+		t.Error("resubmission with other values has same delivery ID as first submission")
+	}
+}
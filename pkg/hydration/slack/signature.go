@@ -0,0 +1,51 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRequestAge is how old signed requests can be to prevent replay attacks.
+const maxRequestAge = 5 * time.Minute
+
+// Errors returned by VerifySignature.
+var (
+	ErrSignatureMissing = errors.New("request signature is missing")
+	ErrSignatureExpired = errors.New("request timestamp is too old")
+	ErrSignatureInvalid = errors.New("request signature does not match")
+)
+
+// VerifySignature verifies X-Slack-Signature header of request body with signing secret.
+func VerifySignature(signingSecret string, header http.Header, body []byte, now time.Time) error {
+	signature := header.Get("X-Slack-Signature")
+	sTimestamp := header.Get("X-Slack-Request-Timestamp")
+	if len(signature) == 0 || len(sTimestamp) == 0 {
+		return ErrSignatureMissing
+	}
+
+	timestamp, err := strconv.ParseInt(sTimestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureMissing
+	}
+
+	age := now.Sub(time.Unix(timestamp, 0))
+	if age > maxRequestAge || age < -maxRequestAge {
+		return ErrSignatureExpired
+	}
+
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte("v0:" + sTimestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrSignatureInvalid
+	}
+
+	return nil
+}
@@ -1,21 +1,35 @@
 package slack
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"strings"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/metrics"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
+// httpClient is client of Slack calls. Its timeout bounds calls whose context is not canceled.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
 /*
 Message describes the format for posting message.
 */
 type (
 	// Config describes config for slack.
 	Config struct {
-		Token string `json:"token"`
+		Token          string `json:"token"`
+		SigningSecret  string `json:"signing_secret"`
+		MetadataSecret string `json:"metadata_secret"`
 	}
 
 	Message struct {
@@ -23,23 +37,31 @@ type (
 		Text    string `json:"text"`
 		Blocks  string `json:"blocks"`
 	}
+
+	// Response describes common fields of Web API responses.
+	Response struct {
+		Ok      bool   `json:"ok"`
+		Error   string `json:"error"`
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
 )
 
 /*
 PostMessage posts message to slack.
 */
-func PostMessage(token string, message Message) ([]byte, error) {
+func PostMessage(ctx context.Context, token string, message Message) (body []byte, err error) {
+	ctx, end := startCall(ctx, "chat.postMessage")
+	defer end(&body, &err)
 
 	postMessageJSON, _ := json.Marshal(message)
 
-	client := &http.Client{}
-	req, _ := http.NewRequest("POST", "https://slack.com/api/chat.postMessage", strings.NewReader(string(postMessageJSON)))
+	req, _ := http.NewRequestWithContext(ctx, "POST", "https://slack.com/api/chat.postMessage", strings.NewReader(string(postMessageJSON)))
 	req.Header.Add("Content-type", "application/json")
 	req.Header.Add("Authorization", "Bearer "+token)
 
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		fmt.Println(err)
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -49,14 +71,15 @@ func PostMessage(token string, message Message) ([]byte, error) {
 /*
 PostJSON posts request to slack.
 */
-func PostJSON(token string, command string, contentType string, paramJSON string) ([]byte, error) {
+func PostJSON(ctx context.Context, token string, command string, contentType string, paramJSON string) (body []byte, err error) {
+	ctx, end := startCall(ctx, command)
+	defer end(&body, &err)
 
-	client := &http.Client{}
-	req, _ := http.NewRequest("POST", "https://slack.com/api/"+command, strings.NewReader(paramJSON))
+	req, _ := http.NewRequestWithContext(ctx, "POST", "https://slack.com/api/"+command, strings.NewReader(paramJSON))
 	req.Header.Add("Content-type", contentType)
 	req.Header.Add("Authorization", "Bearer "+token)
 
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -65,19 +88,116 @@ func PostJSON(token string, command string, contentType string, paramJSON string
 }
 
 /*
-PostJSON posts request to slack.
+PostReader posts request body read from params to slack, so that large files are sent without loading them into memory.
 */
-func PostBuffer(token string, command string, contentType string, params *bytes.Buffer) ([]byte, error) {
+func PostReader(ctx context.Context, token string, command string, contentType string, params io.Reader) (body []byte, err error) {
+	ctx, end := startCall(ctx, command)
+	defer end(&body, &err)
 
-	client := &http.Client{}
-	req, _ := http.NewRequest("POST", "https://slack.com/api/"+command, params)
+	req, _ := http.NewRequestWithContext(ctx, "POST", "https://slack.com/api/"+command, params)
 	req.Header.Add("Content-type", contentType)
 	req.Header.Add("Authorization", "Bearer "+token)
 
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	return ioutil.ReadAll(resp.Body)
 }
+
+// ErrFileTooLarge is returned when downloaded file is larger than limit.
+var ErrFileTooLarge = errors.New("file is larger than limit")
+
+/*
+Download downloads private file from fileURL with token. ErrFileTooLarge is returned if file is larger than maxSize.
+*/
+func Download(ctx context.Context, token string, fileURL string, maxSize int64) (body []byte, err error) {
+	ctx, end := startCall(ctx, downloadMethod)
+	defer end(nil, &err)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed downloading file: %s", resp.Status)
+	}
+	// Slack answers login page instead of file if token lacks files:read scope.
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		return nil, errors.New("Failed downloading file: login page was returned, so files:read scope may be missing")
+	}
+
+	body, err = ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, ErrFileTooLarge
+	}
+	return body, nil
+}
+
+/*
+PostResponseURL posts message to response_url of interaction or slash command.
+*/
+func PostResponseURL(ctx context.Context, responseURL string, paramJSON string) (body []byte, err error) {
+	ctx, end := startCall(ctx, responseURLMethod)
+	defer end(nil, &err)
+
+	req, _ := http.NewRequestWithContext(ctx, "POST", responseURL, strings.NewReader(paramJSON))
+	req.Header.Add("Content-type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Method labels of requests which are not Web API methods.
+const (
+	responseURLMethod = "response_url"
+	downloadMethod    = "files.download"
+)
+
+// startCall starts span of Slack API call, and returns ctx carrying span and function which ends span.
+// End function records call with error code of response, and logs it at debug level with request ID in ctx.
+// Response body is not decoded if body is nil, as posts to response_url do not answer JSON.
+func startCall(ctx context.Context, method string) (context.Context, func(body *[]byte, err *error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "slack "+method, attribute.String("slack.method", method))
+
+	return ctx, func(body *[]byte, err *error) {
+		errorCode := ""
+		if *err != nil {
+			errorCode = metrics.ErrorTransport
+		} else if body != nil {
+			var response Response
+			if json.Unmarshal(*body, &response) != nil {
+				errorCode = "invalid_response"
+			} else if !response.Ok {
+				errorCode = response.Error
+			}
+		}
+
+		elapsed := time.Since(start)
+		metrics.ObserveSlackCall(method, errorCode, elapsed)
+		slog.DebugContext(ctx, "Called Slack API", "method", method, "error", errorCode, "duration_ms", elapsed.Milliseconds())
+
+		if len(errorCode) > 0 {
+			span.SetAttributes(attribute.String("slack.error", errorCode))
+			span.SetStatus(codes.Error, errorCode)
+		}
+		tracing.End(span, *err)
+	}
+}
@@ -0,0 +1,113 @@
+// Package tracing records OpenTelemetry spans of requests, background jobs, queries and Slack calls.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is name of tracer which records spans of this app.
+const instrumentationName = "github.com/pirosuke/slack-bot-hydration"
+
+// Config describes where spans are exported.
+type Config struct {
+	// Endpoint is host and port of OTLP/HTTP collector, e.g. localhost:4318. Tracing is disabled if empty.
+	Endpoint string `json:"endpoint"`
+	// Insecure sends spans over HTTP instead of HTTPS.
+	Insecure bool `json:"insecure"`
+	// SampleRatio is ratio of traces which are recorded, from 0 to 1.
+	SampleRatio float64 `json:"sample_ratio"`
+}
+
+// Enabled returns true if spans are exported.
+func (config Config) Enabled() bool {
+	return len(config.Endpoint) > 0
+}
+
+// Setup installs tracer provider which exports spans to OTLP collector as serviceName.
+// Spans are not recorded if tracing is not enabled in config.
+// Returned shutdown exports spans which are not sent yet.
+func Setup(ctx context.Context, config Config, serviceName string) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !config.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(config.Endpoint)}
+	if config.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("Failed creating OTLP exporter: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(newResource(serviceName)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewInMemory installs tracer provider which keeps all spans in returned exporter, for tests.
+// Spans are added to exporter when they end.
+func NewInMemory(serviceName string) *tracetest.InMemoryExporter {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(newResource(serviceName)),
+	))
+
+	return exporter
+}
+
+// newResource describes this app in exported spans.
+func newResource(serviceName string) *resource.Resource {
+	return resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version.Version),
+	)
+}
+
+// Start starts span as child of span in ctx, and returns ctx carrying new span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err in span if it is not nil, and ends span.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns ID of trace in ctx, or empty string if ctx is not traced.
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
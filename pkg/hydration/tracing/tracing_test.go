@@ -0,0 +1,124 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/interfaces"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/jobs"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/repositories"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/router"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/slack"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/tracing"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// pingRepository answers Ping without database. Other methods are not used.
+type pingRepository struct {
+	interfaces.HydrationRepository
+}
+
+func (pingRepository) Ping() error {
+	return nil
+}
+
+const blockActions = `{
+	"type": "block_actions",
+	"team": {"id": "T1"},
+	"user": {"id": "U1", "username": "alice"},
+	"response_url": "%s",
+	"actions": [{"action_id": "hydration__repeat_drink", "value": "42", "action_ts": "1589268540.871533"}]
+}`
+
+func TestInteractionIsParentOfJobQueryAndSlackCall(t *testing.T) {
+	exporter := tracing.NewInMemory("test")
+
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer slackServer.Close()
+
+	background := &jobs.Runner{}
+	repo := repositories.InstrumentedRepository{HydrationRepository: pingRepository{}}
+
+	r := router.New()
+	r.Use(router.Trace())
+	r.Handle(slack.InteractionBlockActions, "hydration__repeat_drink", func(c echo.Context, interaction slack.Interaction) error {
+		responseURL := interaction.(slack.BlockActionsPayload).ResponseURL
+
+		background.Go(c.Request().Context(), jobs.Describe("repeat drink", "hydration_id", 42), func(ctx context.Context) {
+			if err := repo.WithContext(ctx).Ping(); err != nil {
+				t.Error(err)
+			}
+			if _, err := slack.PostResponseURL(ctx, responseURL, `{"text": "done"}`); err != nil {
+				t.Error(err)
+			}
+		})
+
+		return c.String(http.StatusOK, "")
+	})
+
+	form := url.Values{"payload": {strings.Replace(blockActions, "%s", slackServer.URL, 1)}}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	if err := r.Serve(echo.New().NewContext(req, rec)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := background.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+
+	interaction := findSpan(t, spans, "interaction block_actions hydration__repeat_drink")
+	job := findSpan(t, spans, "background job")
+	query := findSpan(t, spans, "db Ping")
+	call := findSpan(t, spans, "slack response_url")
+
+	if interaction.Parent.IsValid() {
+		t.Errorf("interaction span has parent %s, want root", interaction.Parent.SpanID())
+	}
+	checkParent(t, job, interaction)
+	checkParent(t, query, job)
+	checkParent(t, call, job)
+}
+
+// findSpan returns ended span named name.
+func findSpan(t *testing.T, spans map[string]tracetest.SpanStub, name string) tracetest.SpanStub {
+	t.Helper()
+
+	span, ok := spans[name]
+	if !ok {
+		names := make([]string, 0, len(spans))
+		for spanName := range spans {
+			names = append(names, spanName)
+		}
+		t.Fatalf("span %q not found in %v", name, names)
+	}
+	return span
+}
+
+// checkParent fails test unless parent is parent span of child in same trace.
+func checkParent(t *testing.T, child tracetest.SpanStub, parent tracetest.SpanStub) {
+	t.Helper()
+
+	if child.SpanContext.TraceID() != parent.SpanContext.TraceID() {
+		t.Errorf("%s is in trace %s, want %s", child.Name, child.SpanContext.TraceID(), parent.SpanContext.TraceID())
+	}
+	if child.Parent.SpanID() != parent.SpanContext.SpanID() {
+		t.Errorf("parent of %s is %s, want %s (%s)", child.Name, child.Parent.SpanID(), parent.Name, parent.SpanContext.SpanID())
+	}
+}
@@ -0,0 +1,157 @@
+package validation
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/i18n"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/models"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/routing"
+)
+
+// Block IDs of forms which errors are attached to and limits of submitted values.
+const (
+	BlockDrink       = "drink"
+	BlockAmount      = "amount"
+	BlockDrankDate   = "drank_date"
+	BlockDrankTime   = "drank_time"
+	BlockDailyGoal   = "daily_goal"
+	BlockPostMode    = "post_mode"
+	BlockPostChannel = "post_channel"
+	BlockLocale      = "locale"
+	MinAmount        = 1
+	MaxAmount        = 2000
+	MinDailyGoal     = 100
+	MaxDailyGoal     = 10000
+	maxDrinkRunes    = 255
+)
+
+// PostModeDefault is value of post mode option which follows workspace routing.
+const PostModeDefault = "default"
+
+// LocaleDefault is value of locale option which follows locale of Slack.
+const LocaleDefault = "default"
+
+// Formats of datepicker and timepicker values.
+const (
+	DateFormat = "2006-01-02"
+	TimeFormat = "15:04"
+)
+
+type (
+	// HydrationForm describes raw values submitted from record form.
+	// Locale is locale of error messages, and Location is time zone which date and time are in.
+	// Time zone of now is used if Location is nil.
+	HydrationForm struct {
+		Drink     string
+		Amount    string
+		DrankDate string
+		DrankTime string
+		Locale    string
+		Location  *time.Location
+	}
+
+	// SettingsForm describes raw values submitted from settings form.
+	// Locale is locale of error messages, and SelectedLocale is submitted locale.
+	SettingsForm struct {
+		DailyGoal      string
+		PostMode       string
+		PostChannel    string
+		SelectedLocale string
+		Locale         string
+	}
+
+	// Errors maps block ID to error message shown under the block.
+	Errors map[string]string
+)
+
+// Validate validates submitted values and returns hydration filled with them.
+// Drinking time is returned in time zone of now.
+func (form HydrationForm) Validate(now time.Time) (models.Hydration, Errors) {
+	errs := Errors{}
+	hydration := models.Hydration{}
+
+	hydration.Drink = strings.TrimSpace(form.Drink)
+	if len(hydration.Drink) == 0 {
+		errs[BlockDrink] = i18n.T(form.Locale, "validation.drink_required")
+	} else if len([]rune(hydration.Drink)) > maxDrinkRunes {
+		errs[BlockDrink] = i18n.T(form.Locale, "validation.drink_too_long", maxDrinkRunes)
+	}
+
+	amount, err := strconv.ParseInt(form.Amount, 10, 64)
+	if err != nil {
+		errs[BlockAmount] = i18n.T(form.Locale, "validation.amount_required")
+	} else if amount < MinAmount || amount > MaxAmount {
+		errs[BlockAmount] = i18n.T(form.Locale, "validation.amount_range", MinAmount, MaxAmount)
+	}
+	hydration.Amount = amount
+
+	if _, err := time.Parse(DateFormat, form.DrankDate); err != nil {
+		errs[BlockDrankDate] = i18n.T(form.Locale, "validation.date_required")
+	}
+
+	if _, err := time.Parse(TimeFormat, form.DrankTime); err != nil {
+		errs[BlockDrankTime] = i18n.T(form.Locale, "validation.time_required")
+	}
+
+	if _, ok := errs[BlockDrankDate]; !ok {
+		if _, ok := errs[BlockDrankTime]; !ok {
+			loc := form.Location
+			if loc == nil {
+				loc = now.Location()
+			}
+
+			drankAt, _ := time.ParseInLocation(DateFormat+" "+TimeFormat, form.DrankDate+" "+form.DrankTime, loc)
+			if drankAt.After(now) {
+				errs[BlockDrankTime] = i18n.T(form.Locale, "validation.time_future")
+			}
+			hydration.DrankAt = drankAt.In(now.Location())
+		}
+	}
+
+	return hydration, errs
+}
+
+// Validate validates submitted values and returns settings filled with them.
+func (form SettingsForm) Validate() (models.UserSettings, Errors) {
+	errs := Errors{}
+	settings := models.UserSettings{}
+
+	dailyGoal, err := strconv.ParseInt(strings.TrimSpace(form.DailyGoal), 10, 64)
+	if err != nil || dailyGoal < MinDailyGoal || dailyGoal > MaxDailyGoal {
+		errs[BlockDailyGoal] = i18n.T(form.Locale, "validation.daily_goal_range", MinDailyGoal, MaxDailyGoal)
+	}
+	settings.DailyGoal = dailyGoal
+
+	if form.PostMode != PostModeDefault {
+		if !routing.IsValidMode(form.PostMode) {
+			errs[BlockPostMode] = i18n.T(form.Locale, "validation.post_mode_required")
+		} else {
+			settings.PostMode = form.PostMode
+		}
+	}
+
+	if settings.PostMode == routing.ModeChannel && len(form.PostChannel) == 0 {
+		errs[BlockPostChannel] = i18n.T(form.Locale, "validation.post_channel_needed")
+	}
+	settings.PostChannel = form.PostChannel
+
+	if form.SelectedLocale != LocaleDefault {
+		if !i18n.IsSupported(form.SelectedLocale) {
+			errs[BlockLocale] = i18n.T(form.Locale, "validation.locale_required")
+		} else {
+			settings.Locale = form.SelectedLocale
+		}
+	}
+
+	return settings, errs
+}
+
+// Response returns view_submission response which shows errors on modal.
+func (errs Errors) Response() map[string]interface{} {
+	return map[string]interface{}{
+		"response_action": "errors",
+		"errors":          errs,
+	}
+}
@@ -0,0 +1,134 @@
+package validation
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/i18n"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/routing"
+)
+
+func TestHydrationFormValidateLocation(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("time zone data is not available:", err)
+	}
+
+	// server runs in UTC, and it is morning of May 1 in Tokyo.
+	now := time.Date(2020, 5, 1, 1, 0, 0, 0, time.UTC)
+	form := HydrationForm{
+		Drink:     "Water",
+		Amount:    "237",
+		DrankDate: "2020-05-01",
+		DrankTime: "09:30",
+		Locale:    i18n.English,
+		Location:  tokyo,
+	}
+
+	hydration, errs := form.Validate(now)
+	if len(errs) > 0 {
+		t.Fatalf("errors = %v", errs)
+	}
+	want := time.Date(2020, 5, 1, 0, 30, 0, 0, time.UTC)
+	if !hydration.DrankAt.Equal(want) || hydration.DrankAt.Location() != time.UTC {
+		t.Errorf("DrankAt = %s, want %s in time zone of server", hydration.DrankAt, want)
+	}
+
+	form.Location = nil
+	if _, errs := form.Validate(now); errs[BlockDrankTime] == "" {
+		t.Error("09:30 in time zone of server is accepted though it is in future")
+	}
+}
+
+func TestHydrationFormValidate(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	valid := HydrationForm{
+		Drink:     " Water ",
+		Amount:    "237",
+		DrankDate: "2020-05-01",
+		DrankTime: "09:30",
+		Locale:    i18n.English,
+	}
+
+	hydration, errs := valid.Validate(now)
+	if len(errs) > 0 {
+		t.Fatalf("errors = %v", errs)
+	}
+	if hydration.Drink != "Water" || hydration.Amount != 237 || !hydration.DrankAt.Equal(time.Date(2020, 5, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("hydration = %+v", hydration)
+	}
+
+	tests := []struct {
+		name   string
+		change func(form *HydrationForm)
+		block  string
+	}{
+		{"empty drink", func(form *HydrationForm) { form.Drink = "  " }, BlockDrink},
+		{"too long drink", func(form *HydrationForm) { form.Drink = strings.Repeat("水", maxDrinkRunes+1) }, BlockDrink},
+		{"empty amount", func(form *HydrationForm) { form.Amount = "" }, BlockAmount},
+		{"amount below minimum", func(form *HydrationForm) { form.Amount = strconv.Itoa(MinAmount - 1) }, BlockAmount},
+		{"amount above maximum", func(form *HydrationForm) { form.Amount = strconv.Itoa(MaxAmount + 1) }, BlockAmount},
+		{"invalid date", func(form *HydrationForm) { form.DrankDate = "2020-13-01" }, BlockDrankDate},
+		{"invalid time", func(form *HydrationForm) { form.DrankTime = "25:00" }, BlockDrankTime},
+		{"future time", func(form *HydrationForm) { form.DrankTime = "12:01" }, BlockDrankTime},
+	}
+
+	for _, test := range tests {
+		form := valid
+		test.change(&form)
+
+		_, errs := form.Validate(now)
+		if len(errs) != 1 || errs[test.block] == "" {
+			t.Errorf("%s: errors = %v, want error of %s", test.name, errs, test.block)
+		}
+	}
+
+	for _, amount := range []int{MinAmount, MaxAmount} {
+		form := valid
+		form.Amount = strconv.Itoa(amount)
+		if _, errs := form.Validate(now); len(errs) > 0 {
+			t.Errorf("amount %d: errors = %v", amount, errs)
+		}
+	}
+}
+
+func TestSettingsFormValidate(t *testing.T) {
+	valid := SettingsForm{
+		DailyGoal:      "2000",
+		PostMode:       PostModeDefault,
+		SelectedLocale: LocaleDefault,
+		Locale:         i18n.English,
+	}
+
+	settings, errs := valid.Validate()
+	if len(errs) > 0 {
+		t.Fatalf("errors = %v", errs)
+	}
+	if settings.DailyGoal != 2000 || settings.PostMode != "" || settings.Locale != "" {
+		t.Errorf("settings = %+v", settings)
+	}
+
+	tests := []struct {
+		name   string
+		change func(form *SettingsForm)
+		block  string
+	}{
+		{"daily goal below minimum", func(form *SettingsForm) { form.DailyGoal = strconv.Itoa(MinDailyGoal - 1) }, BlockDailyGoal},
+		{"daily goal above maximum", func(form *SettingsForm) { form.DailyGoal = strconv.Itoa(MaxDailyGoal + 1) }, BlockDailyGoal},
+		{"unknown post mode", func(form *SettingsForm) { form.PostMode = "everywhere" }, BlockPostMode},
+		{"channel mode without channel", func(form *SettingsForm) { form.PostMode = routing.ModeChannel }, BlockPostChannel},
+		{"unsupported locale", func(form *SettingsForm) { form.SelectedLocale = "xx" }, BlockLocale},
+	}
+
+	for _, test := range tests {
+		form := valid
+		test.change(&form)
+
+		_, errs := form.Validate()
+		if len(errs) != 1 || errs[test.block] == "" {
+			t.Errorf("%s: errors = %v, want error of %s", test.name, errs, test.block)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package version holds build information set with -ldflags, e.g.
+// -ldflags "-X github.com/pirosuke/slack-bot-hydration/pkg/hydration/version.Version=v1.2.0".
+package version
+
+import "runtime"
+
+// Build information. They are overwritten at build time.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes build of running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns build information of running binary.
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+}
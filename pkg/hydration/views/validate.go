@@ -0,0 +1,130 @@
+package views
+
+import (
+	"fmt"
+)
+
+// Limits of Block Kit documented by Slack.
+const (
+	MaxMessageBlocks    = 50
+	MaxViewBlocks       = 100
+	MaxViewTitleRunes   = 24
+	MaxMetadataRunes    = 3000
+	MaxIDRunes          = 255
+	MaxSectionTextRunes = 3000
+	MaxFieldTextRunes   = 2000
+	MaxSectionFields    = 10
+	MaxHeaderTextRunes  = 150
+	MaxActionsElements  = 25
+	MaxContextElements  = 10
+	MaxButtonTextRunes  = 75
+	MaxButtonValueRunes = 2000
+)
+
+// Validate checks that view, blocks or block element does not exceed Block Kit limits.
+// Top level array is treated as blocks of message.
+func Validate(view interface{}) error {
+	if blocks, ok := view.([]interface{}); ok && len(blocks) > MaxMessageBlocks {
+		return fmt.Errorf("message has %d blocks, max %d", len(blocks), MaxMessageBlocks)
+	}
+
+	return validateNode("", view)
+}
+
+func validateNode(path string, node interface{}) error {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		if err := validateObject(path, value); err != nil {
+			return err
+		}
+		for key, child := range value {
+			if err := validateNode(path+"/"+key, child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, child := range value {
+			if err := validateNode(fmt.Sprintf("%s/%d", path, i), child); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateObject checks limits of one Block Kit object.
+func validateObject(path string, object map[string]interface{}) error {
+	for _, key := range []string{"action_id", "block_id", "callback_id"} {
+		if err := maxRunes(path+"/"+key, stringValue(object[key]), MaxIDRunes); err != nil {
+			return err
+		}
+	}
+
+	switch object["type"] {
+	case "modal":
+		for _, key := range []string{"title", "submit", "close"} {
+			if err := maxRunes(path+"/"+key, textOf(object[key]), MaxViewTitleRunes); err != nil {
+				return err
+			}
+		}
+		if err := maxRunes(path+"/private_metadata", stringValue(object["private_metadata"]), MaxMetadataRunes); err != nil {
+			return err
+		}
+		return maxItems(path+"/blocks", object["blocks"], MaxViewBlocks)
+	case "home":
+		return maxItems(path+"/blocks", object["blocks"], MaxViewBlocks)
+	case "section":
+		if err := maxRunes(path+"/text", textOf(object["text"]), MaxSectionTextRunes); err != nil {
+			return err
+		}
+		if err := maxItems(path+"/fields", object["fields"], MaxSectionFields); err != nil {
+			return err
+		}
+		fields, _ := object["fields"].([]interface{})
+		for i, field := range fields {
+			if err := maxRunes(fmt.Sprintf("%s/fields/%d", path, i), textOf(field), MaxFieldTextRunes); err != nil {
+				return err
+			}
+		}
+	case "header":
+		return maxRunes(path+"/text", textOf(object["text"]), MaxHeaderTextRunes)
+	case "actions":
+		return maxItems(path+"/elements", object["elements"], MaxActionsElements)
+	case "context":
+		return maxItems(path+"/elements", object["elements"], MaxContextElements)
+	case "button":
+		if err := maxRunes(path+"/text", textOf(object["text"]), MaxButtonTextRunes); err != nil {
+			return err
+		}
+		return maxRunes(path+"/value", stringValue(object["value"]), MaxButtonValueRunes)
+	}
+
+	return nil
+}
+
+func maxRunes(path string, text string, limit int) error {
+	if count := len([]rune(text)); count > limit {
+		return fmt.Errorf("%s has %d characters, max %d", path, count, limit)
+	}
+	return nil
+}
+
+func maxItems(path string, node interface{}, limit int) error {
+	items, _ := node.([]interface{})
+	if len(items) > limit {
+		return fmt.Errorf("%s has %d items, max %d", path, len(items), limit)
+	}
+	return nil
+}
+
+// textOf returns text of text object.
+func textOf(node interface{}) string {
+	object, _ := node.(map[string]interface{})
+	return stringValue(object["text"])
+}
+
+func stringValue(node interface{}) string {
+	text, _ := node.(string)
+	return text
+}
@@ -0,0 +1,358 @@
+package views
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/i18n"
+	"github.com/pirosuke/slack-bot-hydration/pkg/hydration/slack"
+)
+
+type (
+	// Set holds parsed view templates keyed by file name.
+	// Templates are read from default files, and files in override directory replace default files of same name.
+	Set struct {
+		defaults        fs.FS
+		overrideDirPath string
+
+		mu        sync.RWMutex
+		templates map[string]*Template
+		snapshot  string
+	}
+
+	// Template is view template parsed from JSON file.
+	// String values may contain text/template actions such as {{.drink}}, and {{t "key" .param}} for localized messages.
+	Template struct {
+		name string
+		root interface{}
+	}
+
+	// textNode is string value of template which contains actions.
+	textNode struct {
+		tmpl   *template.Template
+		mrkdwn bool
+	}
+)
+
+// Load parses and validates all JSON view templates in directory.
+func Load(dirPath string) (*Set, error) {
+	return New(os.DirFS(dirPath), "")
+}
+
+// New parses and validates all JSON view templates in defaults and override directory.
+// Override directory is not used if its path is empty.
+func New(defaults fs.FS, overrideDirPath string) (*Set, error) {
+	set := &Set{
+		defaults:        defaults,
+		overrideDirPath: overrideDirPath,
+	}
+
+	if err := set.Reload(); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// Reload parses templates again. Current templates are kept if any template is invalid.
+func (set *Set) Reload() error {
+	snapshot, err := set.overrideSnapshot()
+	if err != nil {
+		return err
+	}
+
+	// invalid files are not parsed again until they change.
+	set.mu.Lock()
+	set.snapshot = snapshot
+	set.mu.Unlock()
+
+	templates, err := set.parse()
+	if err != nil {
+		return err
+	}
+
+	set.mu.Lock()
+	set.templates = templates
+	set.mu.Unlock()
+
+	return nil
+}
+
+// parse parses default templates and replaces them with templates in override directory.
+func (set *Set) parse() (map[string]*Template, error) {
+	templates, err := parseFS(set.defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(set.overrideDirPath) > 0 {
+		overrides, err := parseFS(os.DirFS(set.overrideDirPath))
+		if err != nil {
+			return nil, err
+		}
+
+		for name, tmpl := range overrides {
+			templates[name] = tmpl
+		}
+	}
+
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("View files do not exist")
+	}
+
+	return templates, nil
+}
+
+// Watch reloads templates whenever files in override directory change, checking them every interval.
+// Result of each reload is passed to onReload. Watching stops when returned function is called.
+func (set *Set) Watch(interval time.Duration, onReload func(err error)) (stop func()) {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				snapshot, err := set.overrideSnapshot()
+				if err != nil {
+					onReload(err)
+					continue
+				}
+
+				set.mu.RLock()
+				changed := snapshot != set.snapshot
+				set.mu.RUnlock()
+
+				if changed {
+					onReload(set.Reload())
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// overrideSnapshot returns names, sizes and modification times of files in override directory.
+func (set *Set) overrideSnapshot() (string, error) {
+	if len(set.overrideDirPath) == 0 {
+		return "", nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(set.overrideDirPath, "*.json"))
+	if err != nil {
+		return "", err
+	}
+
+	var snapshot strings.Builder
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&snapshot, "%s %d %d\n", filepath.Base(path), info.Size(), info.ModTime().UnixNano())
+	}
+
+	return snapshot.String(), nil
+}
+
+// parseFS parses all JSON view templates in root of file system.
+func parseFS(fsys fs.FS) (map[string]*Template, error) {
+	paths, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := map[string]*Template{}
+	for _, path := range paths {
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl, err := Parse(path, content)
+		if err != nil {
+			return nil, err
+		}
+		templates[path] = tmpl
+	}
+
+	return templates, nil
+}
+
+// Names returns sorted file names of templates.
+func (set *Set) Names() []string {
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+
+	var names []string
+	for name := range set.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render renders template in locale with params and returns decoded view.
+func (set *Set) Render(name string, locale string, params map[string]string) (interface{}, error) {
+	set.mu.RLock()
+	tmpl, ok := set.templates[name]
+	set.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("View file does not exist: %s", name)
+	}
+
+	return tmpl.Render(locale, params)
+}
+
+// Parse parses JSON view template and validates it against Slack's block limits.
+func Parse(name string, content []byte) (*Template, error) {
+	var root interface{}
+	if err := json.Unmarshal(content, &root); err != nil {
+		return nil, fmt.Errorf("Invalid view file %s: %v", name, err)
+	}
+
+	parsed, err := parseNode(name, "", root, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// values containing actions are checked after rendering.
+	if err := Validate(parsed); err != nil {
+		return nil, fmt.Errorf("Invalid view file %s: %v", name, err)
+	}
+
+	return &Template{name: name, root: parsed}, nil
+}
+
+// parseNode replaces string values containing actions with parsed templates.
+func parseNode(name string, path string, node interface{}, mrkdwn bool) (interface{}, error) {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		isMrkdwn := value["type"] == "mrkdwn"
+		parsed := map[string]interface{}{}
+		for key, child := range value {
+			parsedChild, err := parseNode(name, path+"/"+key, child, isMrkdwn && key == "text")
+			if err != nil {
+				return nil, err
+			}
+			parsed[key] = parsedChild
+		}
+		return parsed, nil
+	case []interface{}:
+		parsed := make([]interface{}, len(value))
+		for i, child := range value {
+			parsedChild, err := parseNode(name, fmt.Sprintf("%s/%d", path, i), child, false)
+			if err != nil {
+				return nil, err
+			}
+			parsed[i] = parsedChild
+		}
+		return parsed, nil
+	case string:
+		if !strings.Contains(value, "{{") {
+			return value, nil
+		}
+
+		tmpl, err := template.New(name + path).Option("missingkey=error").Funcs(translateFuncs("")).Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid view file %s: %v", name, err)
+		}
+		return textNode{tmpl: tmpl, mrkdwn: mrkdwn}, nil
+	}
+
+	return node, nil
+}
+
+// Render renders template in locale with params and returns decoded view.
+// Params are escaped for mrkdwn in mrkdwn text, and for JSON everywhere as view is built as decoded value.
+// Messages of catalogs are not escaped as they may contain mrkdwn.
+func (tmpl *Template) Render(locale string, params map[string]string) (interface{}, error) {
+	mrkdwnParams := map[string]string{}
+	for key, value := range params {
+		mrkdwnParams[key] = slack.EscapeMrkdwn(value)
+	}
+
+	view, err := renderNode(tmpl.root, translateFuncs(locale), params, mrkdwnParams)
+	if err != nil {
+		return nil, fmt.Errorf("Failed rendering view %s: %v", tmpl.name, err)
+	}
+
+	if err := Validate(view); err != nil {
+		return nil, fmt.Errorf("Rendered view %s exceeds limits: %v", tmpl.name, err)
+	}
+
+	return view, nil
+}
+
+// translateFuncs returns template functions which translate messages into locale.
+func translateFuncs(locale string) template.FuncMap {
+	return template.FuncMap{
+		"t": func(key string, args ...string) string {
+			formatArgs := make([]interface{}, len(args))
+			for i, arg := range args {
+				formatArgs[i] = arg
+			}
+			return i18n.T(locale, key, formatArgs...)
+		},
+	}
+}
+
+func renderNode(node interface{}, funcs template.FuncMap, params map[string]string, mrkdwnParams map[string]string) (interface{}, error) {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		rendered := map[string]interface{}{}
+		for key, child := range value {
+			renderedChild, err := renderNode(child, funcs, params, mrkdwnParams)
+			if err != nil {
+				return nil, err
+			}
+			rendered[key] = renderedChild
+		}
+		return rendered, nil
+	case []interface{}:
+		rendered := make([]interface{}, len(value))
+		for i, child := range value {
+			renderedChild, err := renderNode(child, funcs, params, mrkdwnParams)
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = renderedChild
+		}
+		return rendered, nil
+	case textNode:
+		data := params
+		if value.mrkdwn {
+			data = mrkdwnParams
+		}
+
+		tmpl, err := value.tmpl.Clone()
+		if err != nil {
+			return nil, err
+		}
+
+		var text strings.Builder
+		if err := tmpl.Funcs(funcs).Execute(&text, data); err != nil {
+			return nil, err
+		}
+		return text.String(), nil
+	}
+
+	return node, nil
+}